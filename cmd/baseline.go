@@ -0,0 +1,89 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// baselineFilter compares the chain hash of each result with one recorded
+// for its input URL by an earlier run, passing on only the results whose
+// chain drifted, each marked with a failure. When no baseline exists yet it
+// records one instead and passes nothing on.
+type baselineFilter struct {
+	tracer.Reporter
+	path     string
+	hashes   map[string]string
+	record   bool
+	recorded int
+}
+
+// newBaselineFilter loads the baseline at path, or prepares to record one if
+// the file does not exist.
+func newBaselineFilter(path string, next tracer.Reporter) (*baselineFilter, error) {
+	b := &baselineFilter{Reporter: next, path: path, hashes: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		b.record = true
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &b.hashes); err != nil {
+		return nil, fmt.Errorf("error parsing baseline %s: %v", path, err)
+	}
+	return b, nil
+}
+
+func (b *baselineFilter) Report(result *tracer.TraceResult) error {
+	if b.record {
+		b.hashes[result.InputURL] = result.ChainHash
+		b.recorded++
+		return nil
+	}
+
+	want, ok := b.hashes[result.InputURL]
+	if !ok {
+		slog.Warn("no baseline recorded, skipping", "url", result.InputURL)
+		return nil
+	}
+	if want == result.ChainHash {
+		return nil
+	}
+
+	result.Failures = append(result.Failures, fmt.Sprintf("chain differs from baseline %s (hash was %s, now %s)", b.path, want, result.ChainHash))
+	return b.Reporter.Report(result)
+}
+
+// Flush writes the baseline when one was being recorded.
+func (b *baselineFilter) Flush() error {
+	if b.record {
+		data, err := json.MarshalIndent(b.hashes, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(b.path, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+		slog.Info("recorded baseline", "count", b.recorded, "path", b.path)
+	}
+	return b.Reporter.Flush()
+}
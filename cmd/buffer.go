@@ -0,0 +1,65 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// defaultMaxBuffered is the default --max-buffered-results.
+const defaultMaxBuffered = 100000
+
+// bufferingModes returns the flags in use whose output holds every result in
+// memory until the batch ends.
+func bufferingModes() []string {
+	var modes []string
+	if ordered {
+		modes = append(modes, "--ordered")
+	}
+	if harPath != "" {
+		modes = append(modes, "--output-har")
+	}
+	return modes
+}
+
+// bufferLimitError explains that count results are too many to buffer.
+func bufferLimitError(modes []string, count int) error {
+	return fmt.Errorf("with %s every result is kept in memory until the batch ends, and %d results exceed --max-buffered-results %d; "+
+		"drop --ordered to stream results as they complete and sort them afterwards, write JSON or --sqlite rather than --output-har, "+
+		"split the input into smaller batches, or raise --max-buffered-results",
+		strings.Join(modes, " and "), count, maxBuffered)
+}
+
+// bufferGuard fails once more than limit results have been reported, before
+// they are passed to the buffering Reporter it wraps. It protects the
+// --output-har file of --repl and --server-stdin sessions, whose size is not
+// known up front; batches are checked before tracing starts instead.
+type bufferGuard struct {
+	tracer.Reporter
+	modes    []string
+	limit    int
+	reported int
+}
+
+func (g *bufferGuard) Report(result *tracer.TraceResult) error {
+	g.reported++
+	if g.reported > g.limit {
+		return bufferLimitError(g.modes, g.reported)
+	}
+	return g.Reporter.Report(result)
+}
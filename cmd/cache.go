@@ -0,0 +1,51 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"log/slog"
+
+	"github.com/kkirsche/urltrace/tracer"
+	"github.com/spf13/cobra"
+)
+
+// cacheClearCmd removes every response stored in --cache-dir.
+var cacheClearCmd = &cobra.Command{
+	Use:   "cache-clear",
+	Short: "cache-clear removes all responses stored in --cache-dir",
+	Long: `cache-clear removes all responses stored by urltrace in the directory given
+by --cache-dir. The command may be used like so:
+
+urltrace cache-clear --cache-dir ~/.cache/urltrace`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cacheDir == "" {
+			log.Fatalln("--cache-dir is required")
+		}
+
+		cache, err := tracer.NewCache(cacheDir)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := cache.Clear(); err != nil {
+			log.Fatalf("error clearing cache: %s", err.Error())
+		}
+		slog.Info("cleared cache", "dir", cacheDir)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(cacheClearCmd)
+}
@@ -0,0 +1,64 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the name of the environment variable bound to each flag.
+const envPrefix = "URLTRACE_"
+
+// envName returns the environment variable bound to the named flag, e.g.
+// URLTRACE_CHAIN_TIMEOUT for --chain-timeout.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// fromEnv records the flags applyEnv set from the environment.
+var fromEnv = map[string]bool{}
+
+// applyEnv sets each flag which was not given on the command line from its
+// environment variable, so that flags take precedence over the environment.
+func applyEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %v", value, envName(f.Name), setErr)
+			return
+		}
+		fromEnv[f.Name] = true
+	})
+	return err
+}
+
+// onCommandLine reports whether the named flag was given on the command line,
+// rather than set from the environment or left at its default. Conflicts
+// between flags are only errors when both were given explicitly.
+func onCommandLine(flags *pflag.FlagSet, name string) bool {
+	return flags.Changed(name) && !fromEnv[name]
+}
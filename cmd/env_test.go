@@ -0,0 +1,61 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestAliasBeatsEnvOfItsTwin(t *testing.T) {
+	t.Setenv("URLTRACE_JSON_INCLUDE_SECRETS", "false")
+
+	var secrets bool
+	flags := pflag.NewFlagSet("urltrace", pflag.ContinueOnError)
+	flags.SetNormalizeFunc(normalizeFlagName)
+	flags.BoolVar(&secrets, "json-include-secrets", false, "")
+	if err := flags.Parse([]string{"--include-secrets"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if !secrets {
+		t.Error("URLTRACE_JSON_INCLUDE_SECRETS=false overrode --include-secrets")
+	}
+}
+
+func TestEnvIsNotOnCommandLine(t *testing.T) {
+	t.Setenv("URLTRACE_OUTPUT", "json")
+
+	var out string
+	flags := pflag.NewFlagSet("urltrace", pflag.ContinueOnError)
+	flags.StringVar(&out, "output", "text", "")
+	if err := flags.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if out != "json" {
+		t.Errorf("output = %q, want json from URLTRACE_OUTPUT", out)
+	}
+	if onCommandLine(flags, "output") {
+		t.Error("URLTRACE_OUTPUT counted as --output given on the command line")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// failureTracker passes results through to a Reporter while noting whether
+// any of them failed a policy check, so the process can exit non-zero, which
+// URLs were abandoned by --worker-timeout or ended on a scheme other than
+// requiredScheme, and the final status of the last result for --status-only.
+type failureTracker struct {
+	tracer.Reporter
+	failed      bool
+	abandoned   []string
+	results     int
+	finalStatus int
+
+	requiredScheme string
+	wrongScheme    []string
+}
+
+func (f *failureTracker) Report(result *tracer.TraceResult) error {
+	if len(result.Failures) > 0 {
+		f.failed = true
+	}
+	f.results++
+	f.finalStatus = tracer.FinalStatus(result)
+	var abandoned *tracer.AbandonedError
+	if errors.As(result.Err, &abandoned) {
+		f.abandoned = append(f.abandoned, result.InputURL)
+	}
+	if scheme := tracer.FinalScheme(result); f.requiredScheme != "" && result.Err == nil && scheme != "" && scheme != f.requiredScheme {
+		f.wrongScheme = append(f.wrongScheme, fmt.Sprintf("%s ended at %s (%s)", result.InputURL, result.Final().URL, scheme))
+	}
+	return f.Reporter.Report(result)
+}
+
+// statusExitCode maps a final status code to the exit code of --status-only:
+// 0 for 2xx, 3 for 3xx, 4 for 4xx and 5 for 5xx. Any other status, or no
+// response at all, exits 1.
+func statusExitCode(status int) int {
+	switch status / 100 {
+	case 2:
+		return 0
+	case 3, 4, 5:
+		return status / 100
+	default:
+		return 1
+	}
+}
+
+// teeReporter passes every result to each of its Reporters in turn.
+type teeReporter []tracer.Reporter
+
+func (t teeReporter) Report(result *tracer.TraceResult) error {
+	for _, r := range t {
+		if err := r.Report(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t teeReporter) Flush() error {
+	for _, r := range t {
+		if err := r.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+func TestAbandonedURLsAreListed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	reporter := &failureTracker{Reporter: discardReporter{}}
+	tr, err := tracer.New(tracer.Options{
+		WorkerTimeout:  50 * time.Millisecond,
+		Retries:        3,
+		RetryBaseDelay: 10 * time.Second,
+		RetryMaxDelay:  10 * time.Second,
+		Reporter:       reporter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(context.Background(), []string{srv.URL + "/slow", srv.URL + "/fast"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reporter.abandoned) != 1 || reporter.abandoned[0] != srv.URL+"/slow" {
+		t.Errorf("abandoned = %v, want [%s/slow]", reporter.abandoned, srv.URL)
+	}
+}
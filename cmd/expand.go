@@ -0,0 +1,185 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// maxExpandedURLs caps how many URLs one --expand pattern may produce, so a
+// mistyped range fails rather than exhausting memory.
+const maxExpandedURLs = 1000000
+
+// expandRequests replaces each request by one request per URL its pattern
+// expands to, keeping the method, headers and body.
+func expandRequests(reqs []tracer.Request) ([]tracer.Request, error) {
+	expanded := make([]tracer.Request, 0, len(reqs))
+	for _, req := range reqs {
+		urls, err := expandURL(req.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error expanding %s: %v", req.URL, err)
+		}
+		for _, u := range urls {
+			r := req
+			r.URL = u
+			expanded = append(expanded, r)
+		}
+	}
+	return expanded, nil
+}
+
+// expandURL expands the globs in pattern, in the style of curl: [1-10] is a
+// numeric range, [01-10] keeps leading zeros, [1-100:10] steps by 10, [a-z]
+// is a range of letters and {a,b,c} is a list. Several globs produce every
+// combination, the last varying fastest. Brackets which do not hold a range,
+// such as an IPv6 host, and braces without a comma are kept as they are. A
+// backslash makes the following bracket or brace literal.
+func expandURL(pattern string) ([]string, error) {
+	var parts [][]string
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, []string{literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern) && strings.IndexByte("[]{}", pattern[i+1]) >= 0:
+			i++
+			literal.WriteByte(pattern[i])
+		case c == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unmatched { at offset %d", i)
+			}
+			body := pattern[i+1 : i+end]
+			if !strings.Contains(body, ",") {
+				literal.WriteString(pattern[i : i+end+1])
+			} else {
+				flush()
+				parts = append(parts, strings.Split(body, ","))
+			}
+			i += end
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unmatched [ at offset %d", i)
+			}
+			values, ok, err := expandRange(pattern[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				literal.WriteString(pattern[i : i+end+1])
+			} else {
+				flush()
+				parts = append(parts, values)
+			}
+			i += end
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+
+	count := 1
+	for _, values := range parts {
+		count *= len(values)
+		if count > maxExpandedURLs {
+			return nil, fmt.Errorf("expands to more than %d URLs", maxExpandedURLs)
+		}
+	}
+
+	urls := []string{""}
+	for _, values := range parts {
+		next := make([]string, 0, len(urls)*len(values))
+		for _, prefix := range urls {
+			for _, v := range values {
+				next = append(next, prefix+v)
+			}
+		}
+		urls = next
+	}
+	return urls, nil
+}
+
+// expandRange returns the values of a bracketed range such as 1-10, 01-10,
+// 0-100:25 or a-z. It returns false when body is not a range at all.
+func expandRange(body string) ([]string, bool, error) {
+	step := 1
+	if r, s, ok := strings.Cut(body, ":"); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, false, nil
+		}
+		if n <= 0 {
+			return nil, false, fmt.Errorf("invalid step in [%s], it must be positive", body)
+		}
+		body, step = r, n
+	}
+
+	first, last, ok := strings.Cut(body, "-")
+	if !ok {
+		return nil, false, nil
+	}
+
+	if len(first) == 1 && len(last) == 1 && isLetter(first[0]) && isLetter(last[0]) {
+		if first[0] > last[0] {
+			return nil, false, fmt.Errorf("invalid range [%s], it runs backwards", body)
+		}
+		var values []string
+		for c := int(first[0]); c <= int(last[0]); c += step {
+			values = append(values, string(rune(c)))
+		}
+		return values, true, nil
+	}
+
+	start, err := strconv.Atoi(first)
+	if err != nil || start < 0 {
+		return nil, false, nil
+	}
+	end, err := strconv.Atoi(last)
+	if err != nil || end < 0 {
+		return nil, false, nil
+	}
+	if start > end {
+		return nil, false, fmt.Errorf("invalid range [%s], it runs backwards", body)
+	}
+	if (end-start)/step+1 > maxExpandedURLs {
+		return nil, false, fmt.Errorf("expands to more than %d URLs", maxExpandedURLs)
+	}
+
+	width := 0
+	if len(first) > 1 && first[0] == '0' {
+		width = len(first)
+	}
+	values := make([]string, 0, (end-start)/step+1)
+	for n := start; n <= end; n += step {
+		values = append(values, fmt.Sprintf("%0*d", width, n))
+	}
+	return values, true, nil
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
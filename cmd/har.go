@@ -0,0 +1,56 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// harFileReporter writes a HAR document of the whole batch, or session, to a
+// file once every result has been reported. The file is only created then, so
+// a run which stops early leaves no empty document behind.
+type harFileReporter struct {
+	*tracer.HARReporter
+	path string
+	buf  bytes.Buffer
+}
+
+// newHARFileReporter returns a reporter for the HAR file at path, failing
+// when its directory does not exist.
+func newHARFileReporter(path string) (*harFileReporter, error) {
+	dir := filepath.Dir(path)
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	h := &harFileReporter{path: path}
+	h.HARReporter = tracer.NewHARReporter(&h.buf)
+	return h, nil
+}
+
+// Flush creates, or truncates, the HAR file and writes the document to it.
+func (h *harFileReporter) Flush() error {
+	if err := h.HARReporter.Flush(); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, h.buf.Bytes(), 0644)
+}
@@ -0,0 +1,80 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// idleError is returned by a line reader from idleLines when no line arrived
+// within the idle limit.
+type idleError struct {
+	limit time.Duration
+}
+
+func (e *idleError) Error() string {
+	return fmt.Sprintf("no input for %s", e.limit)
+}
+
+// idleLines wraps readLine so that it fails with an *idleError when no line
+// is read within limit of being called. readLine runs in its own goroutine,
+// which is left blocked when the limit is reached, so the caller should exit
+// then. A limit of 0 returns readLine unchanged.
+func idleLines(readLine func() (string, error), limit time.Duration) func() (string, error) {
+	if limit <= 0 {
+		return readLine
+	}
+
+	type lineResult struct {
+		line string
+		err  error
+	}
+	requests := make(chan struct{})
+	results := make(chan lineResult, 1)
+	go func() {
+		for range requests {
+			line, err := readLine()
+			results <- lineResult{line, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	pending := false
+	return func() (string, error) {
+		if !pending {
+			requests <- struct{}{}
+			pending = true
+		}
+
+		timer := time.NewTimer(limit)
+		defer timer.Stop()
+		select {
+		case r := <-results:
+			pending = false
+			return r.line, r.err
+		case <-timer.C:
+			return "", &idleError{limit: limit}
+		}
+	}
+}
+
+// logIdleExit reports that a long-lived mode is exiting as it was idle.
+func logIdleExit(idle *idleError) {
+	slog.Info("exiting after --max-idle-time without input", "max_idle_time", idle.limit)
+}
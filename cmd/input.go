@@ -0,0 +1,124 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// readProxyList reads one proxy URL per line from path. Blank lines and lines
+// starting with # are ignored.
+func readProxyList(path string) ([]*url.URL, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var proxies []*url.URL
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		u, err := url.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		proxies = append(proxies, u)
+	}
+
+	return proxies, scanner.Err()
+}
+
+// jsonlRequest is a single line of --jsonl-input.
+type jsonlRequest struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// readJSONLInput reads one trace request per line from path, or from standard
+// input when path is "-". Blank lines are ignored while invalid lines are
+// logged with their line number and skipped.
+func readJSONLInput(path string) ([]tracer.Request, error) {
+	var r io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		name = path
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var reqs []tracer.Request
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		req, err := parseJSONLRequest(line)
+		if err != nil {
+			slog.Warn("skipping invalid line", "file", name, "line", lineNumber, "error", err)
+			continue
+		}
+		reqs = append(reqs, req)
+	}
+
+	return reqs, scanner.Err()
+}
+
+// parseJSONLRequest parses a single JSON trace request.
+func parseJSONLRequest(line string) (tracer.Request, error) {
+	var jr jsonlRequest
+	if err := json.Unmarshal([]byte(line), &jr); err != nil {
+		return tracer.Request{}, err
+	}
+	if jr.URL == "" {
+		return tracer.Request{}, errors.New("missing url")
+	}
+
+	req := tracer.Request{
+		URL:    jr.URL,
+		Method: strings.ToUpper(jr.Method),
+		Body:   jr.Body,
+	}
+	if len(jr.Headers) > 0 {
+		req.Header = http.Header{}
+		for name, value := range jr.Headers {
+			req.Header.Set(name, value)
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,197 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build kafka
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kkirsche/urltrace/tracer"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// kafkaQueueSize is how many results may await publishing before Report
+	// blocks, holding back further traces until the brokers catch up.
+	kafkaQueueSize = 1000
+
+	// kafkaBatchSize is the most messages sent in one request to the brokers.
+	kafkaBatchSize = 100
+
+	// kafkaShutdownTimeout bounds how long an interrupted urltrace waits for
+	// queued results to be published before exiting.
+	kafkaShutdownTimeout = 10 * time.Second
+)
+
+// kafkaReporter publishes each result as a JSON message to a Kafka topic,
+// keyed by its input URL so that the results for a URL stay in order on one
+// partition. Messages are published in batches from a background goroutine.
+type kafkaReporter struct {
+	writer *kafka.Writer
+	queue  chan kafka.Message
+
+	// pending counts the messages queued but not yet acknowledged.
+	pending sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newKafkaReporter connects to the first reachable of brokers and returns a
+// reporter publishing to topic. An interrupt or SIGTERM waits for queued
+// results to be published before exiting.
+func newKafkaReporter(brokers []string, topic string) (tracer.Reporter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaShutdownTimeout)
+	defer cancel()
+
+	var dialErr error
+	for _, broker := range brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			conn.Close()
+			dialErr = nil
+			break
+		}
+		dialErr = err
+	}
+	if dialErr != nil {
+		return nil, dialErr
+	}
+
+	r := &kafkaReporter{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchSize:    kafkaBatchSize,
+			BatchTimeout: 10 * time.Millisecond,
+		},
+		queue: make(chan kafka.Message, kafkaQueueSize),
+	}
+	go r.publish()
+	go r.flushOnSignal()
+	return r, nil
+}
+
+// Report queues result for publishing, blocking while the queue is full. It
+// returns the error of any earlier message which could not be published.
+func (r *kafkaReporter) Report(result *tracer.TraceResult) error {
+	if err := r.failed(); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	r.pending.Add(1)
+	r.queue <- kafka.Message{Key: []byte(result.InputURL), Value: encoded}
+	return nil
+}
+
+// Flush waits until every queued result has been published, then closes the
+// writer and its connections to the brokers.
+func (r *kafkaReporter) Flush() error {
+	r.pending.Wait()
+	if err := r.failed(); err != nil {
+		r.close()
+		return err
+	}
+	if err := r.close(); err != nil {
+		return fmt.Errorf("error closing Kafka writer: %v", err)
+	}
+	return nil
+}
+
+// close closes the writer, once however often it is called.
+func (r *kafkaReporter) close() error {
+	r.closeOnce.Do(func() {
+		r.closeErr = r.writer.Close()
+	})
+	return r.closeErr
+}
+
+func (r *kafkaReporter) failed() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// publish writes the queued messages, taking as many as are waiting, up to
+// the writer's batch size, in each request to the brokers.
+func (r *kafkaReporter) publish() {
+	batch := make([]kafka.Message, 0, kafkaBatchSize)
+	for msg := range r.queue {
+		batch = append(batch[:0], msg)
+	drain:
+		for len(batch) < cap(batch) {
+			select {
+			case msg := <-r.queue:
+				batch = append(batch, msg)
+			default:
+				break drain
+			}
+		}
+
+		if err := r.writer.WriteMessages(context.Background(), batch...); err != nil {
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = fmt.Errorf("error publishing to Kafka topic %s: %v", r.writer.Topic, err)
+			}
+			r.mu.Unlock()
+		}
+		for range batch {
+			r.pending.Done()
+		}
+	}
+}
+
+// flushOnSignal publishes the queued results before exiting on an interrupt
+// or SIGTERM, giving up after kafkaShutdownTimeout. The exit status is the
+// conventional 128 plus the signal number: 130 for an interrupt and 143 for
+// SIGTERM.
+func (r *kafkaReporter) flushOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	signal.Stop(signals)
+
+	slog.Info("publishing queued results to Kafka before exiting", "queued", len(r.queue))
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(kafkaShutdownTimeout):
+		slog.Warn("gave up publishing queued results to Kafka", "timeout", kafkaShutdownTimeout)
+	}
+	r.close()
+	os.Exit(128 + int(sig.(syscall.Signal)))
+}
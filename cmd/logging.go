@@ -0,0 +1,52 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// logLevel is the minimum level of diagnostics written, set by --log-level.
+var logLevel = new(slog.LevelVar)
+
+// parseLogLevel sets logLevel from a name such as debug, info, warn or error.
+func parseLogLevel(name string) error {
+	if strings.EqualFold(name, "warning") {
+		name = "warn"
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+	}
+	logLevel.Set(level)
+	return nil
+}
+
+// setupLogging writes diagnostics at or above logLevel to w as structured
+// text, tagged with the trace ID when there is one. Results are written
+// separately. Output of the log package, which is used for fatal errors, is
+// logged at error level.
+func setupLogging(w io.Writer) {
+	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevel}))
+	if traceID != "" {
+		logger = logger.With("trace_id", traceID)
+	}
+	slog.SetDefault(logger)
+	slog.SetLogLoggerLevel(slog.LevelError)
+}
@@ -0,0 +1,224 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// defaultCanaryHost is the external host injected by --open-redirect-test.
+// The .invalid top-level domain is reserved and never resolves.
+const defaultCanaryHost = "urltrace-canary.invalid"
+
+// redirectParams are query parameters which commonly hold the URL a page
+// redirects to, matched case-insensitively.
+var redirectParams = []string{
+	"callback", "checkout_url", "continue", "dest", "destination", "go", "goto",
+	"next", "out", "redir", "redirect", "redirect_to", "redirect_uri", "redirect_url",
+	"redirecturl", "return", "return_path", "return_to", "return_url", "returnto",
+	"returnurl", "rurl", "target", "to", "u", "uri", "url", "view",
+}
+
+// redirectPayloads returns the values injected into a redirect parameter,
+// each of which sends a browser to canary if a server redirects to it as is.
+func redirectPayloads(canary string) []string {
+	return []string{
+		"https://" + canary + "/",
+		"//" + canary + "/",
+		`/\` + canary + "/",
+	}
+}
+
+// redirectProbe is one URL traced by --open-redirect-test.
+type redirectProbe struct {
+	target  string
+	param   string
+	payload string
+}
+
+// openRedirectTester turns each input URL into probes with a canary host
+// injected into its redirect parameters, and marks the results of probes
+// whose chain redirected to the canary as failures.
+type openRedirectTester struct {
+	tracer.Reporter
+	canary  string
+	params  []string
+	probes  map[string]redirectProbe
+	targets []string
+
+	// vulnerable lists the probes which redirected to the canary by target.
+	vulnerable map[string][]redirectProbe
+	tested     map[string]int
+}
+
+func newOpenRedirectTester(r tracer.Reporter, canary string, params []string) *openRedirectTester {
+	return &openRedirectTester{
+		Reporter:   r,
+		canary:     strings.ToLower(canary),
+		params:     params,
+		probes:     map[string]redirectProbe{},
+		vulnerable: map[string][]redirectProbe{},
+		tested:     map[string]int{},
+	}
+}
+
+// followFunc refuses to follow a redirect to the canary, so it is never
+// contacted.
+func (o *openRedirectTester) followFunc(prev, next *http.Request) bool {
+	return !o.isCanary(next.URL.Hostname())
+}
+
+func (o *openRedirectTester) isCanary(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host == o.canary || strings.HasSuffix(host, "."+o.canary)
+}
+
+// expand returns a probe request for every redirect parameter of each
+// request and every payload. The parameters named with
+// --open-redirect-param are used, added when missing; otherwise those with a
+// common redirect name or whose value looks like a URL or path.
+func (o *openRedirectTester) expand(reqs []tracer.Request) ([]tracer.Request, error) {
+	var probes []tracer.Request
+	for _, req := range reqs {
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", req.URL, err)
+		}
+
+		params := o.params
+		if len(params) == 0 {
+			params = guessRedirectParams(u.Query())
+		}
+		if len(params) == 0 {
+			return nil, fmt.Errorf("%s has no query parameter which looks like a redirect target, name one with --open-redirect-param", req.URL)
+		}
+
+		o.targets = append(o.targets, req.URL)
+		for _, param := range params {
+			for _, payload := range redirectPayloads(o.canary) {
+				probe := *u
+				probe.RawQuery = setQueryParam(u.RawQuery, param, payload)
+				r := req
+				r.URL = probe.String()
+				// The result's InputURL may be redacted, so the probe is
+				// found by its tag.
+				r.Tag = r.URL
+				o.probes[r.Tag] = redirectProbe{target: req.URL, param: param, payload: payload}
+				probes = append(probes, r)
+			}
+		}
+	}
+	return probes, nil
+}
+
+// guessRedirectParams returns the names in query which commonly hold a
+// redirect target or whose value is a URL or absolute path.
+func guessRedirectParams(query url.Values) []string {
+	var params []string
+	for name, values := range query {
+		known := false
+		for _, p := range redirectParams {
+			if strings.EqualFold(name, p) {
+				known = true
+				break
+			}
+		}
+		looksLikeURL := len(values) > 0 && (strings.HasPrefix(values[0], "/") || strings.Contains(values[0], "://"))
+		if known || looksLikeURL {
+			params = append(params, name)
+		}
+	}
+	sort.Strings(params)
+	return params
+}
+
+// setQueryParam sets every occurrence of name in rawQuery to value, leaving
+// the other parameters as they were, and adds it when missing.
+func setQueryParam(rawQuery, name, value string) string {
+	pair := url.QueryEscape(name) + "=" + url.QueryEscape(value)
+	var pairs []string
+	found := false
+	if rawQuery != "" {
+		pairs = strings.Split(rawQuery, "&")
+	}
+	for i, p := range pairs {
+		key, _, _ := strings.Cut(p, "=")
+		if k, err := url.QueryUnescape(key); err == nil && k == name {
+			pairs[i] = pair
+			found = true
+		}
+	}
+	if !found {
+		pairs = append(pairs, pair)
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Report marks result as a failure when its chain redirected to the canary.
+func (o *openRedirectTester) Report(result *tracer.TraceResult) error {
+	probe, ok := o.probes[result.Tag]
+	if ok {
+		o.tested[probe.target]++
+		if hop, found := o.redirectsToCanary(result); found {
+			o.vulnerable[probe.target] = append(o.vulnerable[probe.target], probe)
+			result.Failures = append(result.Failures, fmt.Sprintf("appears vulnerable to an open redirect: hop %d redirects to %s when %s is %s", hop, o.canary, probe.param, probe.payload))
+		}
+	}
+	return o.Reporter.Report(result)
+}
+
+// redirectsToCanary returns the first hop of result which redirected, or
+// refreshed, to the canary host. Backslashes in a Location are read as
+// slashes, as browsers do.
+func (o *openRedirectTester) redirectsToCanary(result *tracer.TraceResult) (int, bool) {
+	for i, hop := range result.Hops {
+		candidates := []string{hop.RedirectURL}
+		if hop.Location != "" {
+			if base, err := url.Parse(hop.URL); err == nil {
+				if next, err := base.Parse(strings.ReplaceAll(hop.Location, `\`, "/")); err == nil {
+					candidates = append(candidates, next.String())
+				}
+			}
+		}
+		for _, c := range candidates {
+			if u, err := url.Parse(c); err == nil && c != "" && o.isCanary(u.Hostname()) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// logSummary logs whether each tested URL appears vulnerable.
+func (o *openRedirectTester) logSummary() {
+	for _, target := range o.targets {
+		if found := o.vulnerable[target]; len(found) > 0 {
+			var params []string
+			for _, p := range found {
+				params = append(params, p.param+"="+p.payload)
+			}
+			slog.Warn("URL appears vulnerable to an open redirect", "url", target, "canary", o.canary, "redirected_with", strings.Join(params, " "))
+		} else {
+			slog.Info("no open redirect found", "url", target, "probes", o.tested[target])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// discardReporter drops every result.
+type discardReporter struct{}
+
+func (discardReporter) Report(*tracer.TraceResult) error { return nil }
+func (discardReporter) Flush() error                     { return nil }
+
+func TestOpenRedirectFoundWithRedactedParams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", r.URL.Query().Get("next"))
+		w.WriteHeader(http.StatusFound)
+	}))
+	defer srv.Close()
+
+	target := srv.URL + "/?token=abc&next=/"
+	tester := newOpenRedirectTester(discardReporter{}, defaultCanaryHost, nil)
+	reqs, err := tester.expand([]tracer.Request{{URL: target}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := tracer.New(tracer.Options{
+		Reporter:     tester,
+		FollowFunc:   tester.followFunc,
+		RedactParams: tracer.DefaultRedactParams,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.RunRequests(context.Background(), reqs); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := tester.tested[target], len(reqs); got != want {
+		t.Errorf("tested %d probes, want %d", got, want)
+	}
+	if got, want := len(tester.vulnerable[target]), len(reqs); got != want {
+		t.Errorf("%d probes found vulnerable, want %d", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "github.com/kkirsche/urltrace/tracer"
+
+// orderedReporter holds back every result until Flush and then passes them
+// on in the order their URLs were given, however they were traced.
+type orderedReporter struct {
+	tracer.Reporter
+
+	// positions holds the input position of each request in the order it is
+	// traced. When nil the requests are traced in input order.
+	positions []int
+	results   []*tracer.TraceResult
+	reported  int
+}
+
+func (o *orderedReporter) Report(result *tracer.TraceResult) error {
+	pos := o.reported
+	if o.positions != nil {
+		pos = o.positions[o.reported]
+	}
+	o.reported++
+
+	for len(o.results) <= pos {
+		o.results = append(o.results, nil)
+	}
+	o.results[pos] = result
+	return nil
+}
+
+// Flush reports the buffered results in input order, skipping any URL which
+// was never traced, then flushes the wrapped Reporter.
+func (o *orderedReporter) Flush() error {
+	for _, result := range o.results {
+		if result == nil {
+			continue
+		}
+		if err := o.Reporter.Report(result); err != nil {
+			return err
+		}
+	}
+	o.results = nil
+	return o.Reporter.Flush()
+}
@@ -0,0 +1,234 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkirsche/urltrace/tracer"
+	"golang.org/x/term"
+)
+
+// replHelp describes the commands the REPL accepts in place of a URL.
+const replHelp = `Enter a URL to trace it, or one of:
+  :set timeout <seconds|duration>  sets the timeout for each request
+  :set chain-timeout <duration>    limits the time spent on each chain, 0 disables
+  :set retries <n>                 retries a trace after a network error or 429
+  :set method <method>             starts each chain with this method
+  :set header <Name: value>        sends a header on every request, no value removes it
+  :show                            prints the current settings
+  :help                            prints this help
+  :quit                            exits, as does Ctrl-D
+`
+
+// repl traces URLs typed at an interactive prompt, reusing one Tracer, and
+// so its pooled connections, until a setting is changed.
+type repl struct {
+	opts     tracer.Options
+	tracer   *tracer.Tracer
+	method   string
+	out      io.Writer
+	reporter tracer.Reporter
+	sinks    tracer.Reporter
+}
+
+// runREPL reads URLs and commands from stdin until EOF. When stdin is a
+// terminal it offers line editing and history. Each result is also reported
+// to sinks, which are flushed once the session ends.
+func runREPL(opts tracer.Options, sinks tracer.Reporter) (err error) {
+	defer func() {
+		if flushErr := sinks.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	var readLine func() (string, error)
+	var out io.Writer = os.Stdout
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+
+		terminal := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "urltrace> ")
+		readLine = terminal.ReadLine
+		out = terminal
+
+		// The terminal translates newlines for raw mode, so log lines must
+		// go through it too.
+		setupLogging(terminal)
+		defer setupLogging(os.Stderr)
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		readLine = func() (string, error) {
+			if scanner.Scan() {
+				return scanner.Text(), nil
+			}
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+	}
+
+	readLine = idleLines(readLine, maxIdleTime)
+
+	reporter, err := newReporter(output, out)
+	if err != nil {
+		return err
+	}
+	opts.Reporter = nil
+
+	r := &repl{opts: opts, out: out, reporter: reporter, sinks: sinks}
+	if r.tracer, err = tracer.New(opts); err != nil {
+		return err
+	}
+
+	for {
+		line, err := readLine()
+		var idle *idleError
+		if err == io.EOF {
+			return nil
+		} else if errors.As(err, &idle) {
+			logIdleExit(idle)
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case line == ":quit" || line == ":q":
+			return nil
+		case strings.HasPrefix(line, ":"):
+			if err := r.command(line); err != nil {
+				fmt.Fprintf(out, "Error: %s\n", err.Error())
+			}
+		default:
+			req := tracer.Request{URL: line, Method: r.method}
+			result := r.tracer.TraceRequest(context.Background(), req)
+			if err := r.reporter.Report(result); err != nil {
+				return err
+			}
+			if err := r.sinks.Report(result); err != nil {
+				return err
+			}
+			if err := r.reporter.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// command runs a REPL command such as ":set timeout 5".
+func (r *repl) command(line string) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":help":
+		_, err := io.WriteString(r.out, replHelp)
+		return err
+	case ":show":
+		_, err := fmt.Fprintf(r.out, "timeout: %s\nchain-timeout: %s\nretries: %d\nmethod: %s\nheaders: %v\n",
+			r.opts.Timeout, r.opts.ChainTimeout, r.opts.Retries, r.methodName(), r.opts.Header)
+		return err
+	case ":set":
+		if len(fields) < 2 {
+			return fmt.Errorf("usage: :set <setting> <value>, see :help")
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line[len(":set"):]), fields[1]))
+		return r.set(fields[1], value)
+	default:
+		return fmt.Errorf("unknown command %s, see :help", fields[0])
+	}
+}
+
+// set changes one setting, rebuilding the Tracer when its Options change.
+func (r *repl) set(name, value string) error {
+	opts := r.opts
+	switch name {
+	case "timeout":
+		d, err := parseSeconds(value)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %v", value, err)
+		}
+		opts.Timeout = d
+	case "chain-timeout":
+		d, err := parseSeconds(value)
+		if err != nil {
+			return fmt.Errorf("invalid chain timeout %q: %v", value, err)
+		}
+		opts.ChainTimeout = d
+	case "retries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid retries %q, expected a number of at least 0", value)
+		}
+		opts.Retries = n
+	case "method":
+		r.method = strings.ToUpper(value)
+		return nil
+	case "header":
+		i := strings.Index(value, ":")
+		if i <= 0 {
+			return fmt.Errorf("invalid header %q, expected \"Name: value\"", value)
+		}
+		opts.Header = opts.Header.Clone()
+		if v := strings.TrimSpace(value[i+1:]); v != "" {
+			opts.Header.Set(strings.TrimSpace(value[:i]), v)
+		} else {
+			opts.Header.Del(strings.TrimSpace(value[:i]))
+		}
+	default:
+		return fmt.Errorf("unknown setting %q, see :help", name)
+	}
+
+	t, err := tracer.New(opts)
+	if err != nil {
+		return err
+	}
+	r.opts, r.tracer = opts, t
+	return nil
+}
+
+// methodName returns the method each chain starts with.
+func (r *repl) methodName() string {
+	if r.method == "" {
+		return "GET"
+	}
+	return r.method
+}
+
+// parseSeconds parses a duration such as 1m30s or a bare number of seconds.
+func parseSeconds(value string) (time.Duration, error) {
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(n * float64(time.Second)), nil
+	}
+	return time.ParseDuration(value)
+}
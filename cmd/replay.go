@@ -0,0 +1,55 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// newReplay opens the --replay-dir dump, limited to the --since and --until
+// window when given.
+func newReplay(dir, since, until string) (*tracer.Dump, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error opening --replay-dir: %v", err)
+	}
+	dump, err := tracer.NewDump(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if dump.Since, err = parseWindowTime(since); err != nil {
+		return nil, fmt.Errorf("error parsing --since: %v", err)
+	}
+	if dump.Until, err = parseWindowTime(until); err != nil {
+		return nil, fmt.Errorf("error parsing --until: %v", err)
+	}
+	return dump, nil
+}
+
+// parseWindowTime parses an RFC 3339 time, or a duration such as 24h meaning
+// that long ago. An empty value is the zero time.
+func parseWindowTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
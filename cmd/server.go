@@ -0,0 +1,98 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+// serverError is written in place of a result for a request line which could
+// not be parsed, so that the parent process receives a reply to every line.
+type serverError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// serveStdin traces each JSON request line read from in, writing one JSON
+// result line to out as soon as each trace completes, until in is exhausted
+// or, with --max-idle-time, no line arrives for that long. Blank lines are
+// ignored. Each result is also reported to sinks, which are flushed once the
+// session ends.
+func serveStdin(t *tracer.Tracer, in io.Reader, out io.Writer, sinks tracer.Reporter) (err error) {
+	defer func() {
+		if flushErr := sinks.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	// Results are written as with --output json, honoring the same options.
+	reporter, err := newReporter("json", out)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+
+	scanner := bufio.NewScanner(in)
+	readLine := idleLines(func() (string, error) {
+		if scanner.Scan() {
+			return scanner.Text(), nil
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}, maxIdleTime)
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := readLine()
+		var idle *idleError
+		if err == io.EOF {
+			return nil
+		} else if errors.As(err, &idle) {
+			logIdleExit(idle)
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		req, err := parseJSONLRequest(line)
+		if err != nil {
+			if err := enc.Encode(serverError{Line: lineNumber, Error: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result := t.TraceRequest(context.Background(), req)
+		if err := reporter.Report(result); err != nil {
+			return err
+		}
+		if err := sinks.Report(result); err != nil {
+			return err
+		}
+	}
+}
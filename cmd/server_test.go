@@ -0,0 +1,148 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkirsche/urltrace/tracer"
+)
+
+func TestServerStdinReportsToSQLite(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "traces.db")
+	sr, err := newSQLiteReporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := tracer.New(tracer.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.NewReader(`{"url": "` + srv.URL + `/a"}` + "\n" + `{"url": "` + srv.URL + `/b"}` + "\n")
+	if err := serveStdin(tr, in, io.Discard, teeReporter{sr}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM traces`).Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 {
+		t.Errorf("traces has %d rows, want 2", rows)
+	}
+}
+
+func TestServerStdinWritesHAR(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	hr, err := newHARFileReporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("HAR file exists before the session ended: %v", err)
+	}
+	tr, err := tracer.New(tracer.Options{CaptureHeaders: true, CaptureRequests: true, Timing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.NewReader(`{"url": "` + srv.URL + `"}` + "\n")
+	if err := serveStdin(tr, in, io.Discard, teeReporter{hr}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har struct {
+		Log struct {
+			Entries []json.RawMessage `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("invalid HAR file: %v", err)
+	}
+	if len(har.Log.Entries) != 1 {
+		t.Errorf("HAR file has %d entries, want 1", len(har.Log.Entries))
+	}
+}
+
+func TestServerStdinStopsAtBufferLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	hr, err := newHARFileReporter(filepath.Join(t.TempDir(), "session.har"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard := &bufferGuard{Reporter: hr, modes: []string{"--output-har"}, limit: 1}
+	tr, err := tracer.New(tracer.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := `{"url": "` + srv.URL + `"}` + "\n"
+	if err := serveStdin(tr, strings.NewReader(line+line), io.Discard, teeReporter{guard}); err == nil {
+		t.Error("second result was buffered past the limit of 1")
+	}
+}
+
+func TestServerStdinHonorsJSONOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	outputPrefix, jsonFlatten, jsonOmitEmpty = "urltrace_", true, true
+	t.Cleanup(func() { outputPrefix, jsonFlatten, jsonOmitEmpty = "", false, false })
+
+	tr, err := tracer.New(tracer.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := serveStdin(tr, strings.NewReader(`{"url": "`+srv.URL+`"}`+"\n"), &out, teeReporter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(out.String()), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["urltrace_hops.0.status"] != float64(http.StatusOK) {
+		t.Errorf("got %s, want a flat, prefixed result", out.String())
+	}
+	for key, value := range result {
+		if value == false || value == "" || value == float64(0) {
+			t.Errorf("empty field %s was written", key)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kkirsche/urltrace/tracer"
+	_ "modernc.org/sqlite" // registers the pure Go "sqlite" driver
+)
+
+// sqliteMigrations upgrade the --sqlite schema one version at a time. The
+// database's user_version records how many have been applied, so new
+// migrations must only ever be appended.
+var sqliteMigrations = []string{
+	`CREATE TABLE traces (
+		id           INTEGER PRIMARY KEY,
+		traced_at    TEXT NOT NULL,
+		input_url    TEXT NOT NULL,
+		trace_id     TEXT,
+		final_url    TEXT,
+		final_status INTEGER,
+		hop_count    INTEGER NOT NULL,
+		stop_reason  TEXT,
+		warnings     TEXT,
+		failures     TEXT,
+		error        TEXT,
+		chain_hash   TEXT,
+		result_json  TEXT NOT NULL
+	);
+	CREATE INDEX traces_input_url ON traces (input_url, traced_at);
+	CREATE TABLE hops (
+		trace        INTEGER NOT NULL REFERENCES traces (id),
+		hop_index    INTEGER NOT NULL,
+		url          TEXT NOT NULL,
+		host         TEXT NOT NULL,
+		method       TEXT NOT NULL,
+		status       INTEGER NOT NULL,
+		duration_ns  INTEGER,
+		location     TEXT,
+		redirect_url TEXT,
+		labels       TEXT,
+		PRIMARY KEY (trace, hop_index)
+	);`,
+}
+
+// sqliteReporter inserts each result, and its hops, into a SQLite database.
+type sqliteReporter struct {
+	db *sql.DB
+}
+
+// newSQLiteReporter opens, creating if needed, the database at path and
+// brings its schema up to date.
+func newSQLiteReporter(path string) (*sqliteReporter, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error migrating %s: %v", path, err)
+	}
+	return &sqliteReporter{db: db}, nil
+}
+
+// migrateSQLite applies the migrations the database has not yet seen.
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("schema version %d is newer than this urltrace supports (%d)", version, len(sqliteMigrations))
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return err
+		}
+		// PRAGMA does not accept bound parameters.
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Report inserts result and its hops in a single transaction.
+func (r *sqliteReporter) Report(result *tracer.TraceResult) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	var finalURL sql.NullString
+	var finalStatus sql.NullInt64
+	if final := result.Final(); final != nil {
+		finalURL = sql.NullString{String: final.URL, Valid: true}
+		finalStatus = sql.NullInt64{Int64: int64(final.StatusCode), Valid: true}
+	}
+	var errString sql.NullString
+	if result.Err != nil {
+		errString = sql.NullString{String: result.Err.Error(), Valid: true}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO traces (traced_at, input_url, trace_id, final_url, final_status,
+		hop_count, stop_reason, warnings, failures, error, chain_hash, result_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		time.Now().UTC().Format(time.RFC3339Nano), result.InputURL, nullString(result.TraceID),
+		finalURL, finalStatus, len(result.Hops), nullString(result.StopReason),
+		nullString(strings.Join(result.Warnings, "\n")), nullString(strings.Join(result.Failures, "\n")),
+		errString, nullString(result.ChainHash), string(encoded))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for i, hop := range result.Hops {
+		if _, err := tx.Exec(`INSERT INTO hops (trace, hop_index, url, host, method, status,
+			duration_ns, location, redirect_url, labels) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, i, hop.URL, hop.Host, hop.Method, hop.StatusCode, int64(hop.Duration),
+			nullString(hop.Location), nullString(hop.RedirectURL), nullString(strings.Join(hop.Labels, ","))); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Flush closes the database, as every result is committed when reported.
+func (r *sqliteReporter) Flush() error {
+	return r.db.Close()
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,328 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// LabelTrailingSlash marks a hop reached by a redirect which only added or
+	// removed a trailing slash.
+	LabelTrailingSlash = "trailing-slash redirect"
+
+	// LabelSlow marks a hop which took longer than Options.SlowHopThreshold.
+	LabelSlow = "slow"
+
+	// LabelNotModified marks a hop which answered a conditional request with
+	// 304 Not Modified.
+	LabelNotModified = "not-modified"
+
+	// LabelSchemeChange, LabelHostChange, LabelPathChange and
+	// LabelQueryChange mark a hop reached by a redirect which changed that
+	// part of the URL. A redirect may change several parts at once.
+	LabelSchemeChange = "scheme-change"
+	LabelHostChange   = "host-change"
+	LabelPathChange   = "path-change"
+	LabelQueryChange  = "query-change"
+
+	// LabelNormalization marks a hop reached by a redirect to an equivalent
+	// URL, such as one differing only in case, default port, trailing slash,
+	// dot segments or query parameter order.
+	LabelNormalization = "normalization"
+)
+
+// IsTransition reports whether label is one of the redirect transition labels
+// which may be used in Options.FailOnTransitions.
+func IsTransition(label string) bool {
+	switch label {
+	case LabelSchemeChange, LabelHostChange, LabelPathChange, LabelQueryChange, LabelNormalization:
+		return true
+	}
+	return false
+}
+
+// analyze labels the hops of result and records any failures according to
+// opts.
+func analyze(result *TraceResult, opts Options) {
+	for i := range result.Hops {
+		if hop := &result.Hops[i]; hop.StatusCode == http.StatusNotModified {
+			hop.Labels = append(hop.Labels, LabelNotModified)
+		}
+	}
+
+	if opts.SlowHopThreshold > 0 {
+		for i := range result.Hops {
+			hop := &result.Hops[i]
+			if hop.Duration <= opts.SlowHopThreshold {
+				continue
+			}
+
+			hop.Labels = append(hop.Labels, LabelSlow)
+			if opts.FailOnSlow {
+				result.Failures = append(result.Failures, fmt.Sprintf("hop %d took %s, exceeding the slow hop threshold of %s", i, hop.Duration.Round(time.Millisecond), opts.SlowHopThreshold))
+			}
+		}
+	}
+
+	if opts.MaxHops > 0 && len(result.Hops) > opts.MaxHops {
+		urls := make([]string, len(result.Hops))
+		for i, hop := range result.Hops {
+			urls[i] = hop.URL
+		}
+		result.Failures = append(result.Failures, fmt.Sprintf("chain has %d hops, more than the %d allowed: %s", len(result.Hops), opts.MaxHops, strings.Join(urls, " -> ")))
+	}
+
+	expiryWarn := opts.CertExpiryWarn
+	if expiryWarn == 0 && opts.FailOnCertExpiry {
+		expiryWarn = DefaultCertExpiryWarn
+	}
+	for i, hop := range result.Hops {
+		cert := hop.Certificate
+		if cert == nil {
+			continue
+		}
+		if cert.HostMismatch {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("hop %d host %s is not covered by its certificate, which is for %s", i, hop.Host, strings.Join(cert.SANs, ", ")))
+		}
+		if expiryWarn > 0 && time.Until(cert.NotAfter) < expiryWarn {
+			msg := fmt.Sprintf("hop %d certificate for %s expires on %s, in %d days", i, hop.Host, cert.NotAfter.UTC().Format(time.DateOnly), cert.DaysRemaining)
+			if cert.DaysRemaining < 0 {
+				msg = fmt.Sprintf("hop %d certificate for %s expired on %s, %d days ago", i, hop.Host, cert.NotAfter.UTC().Format(time.DateOnly), -cert.DaysRemaining)
+			}
+			if opts.FailOnCertExpiry {
+				result.Failures = append(result.Failures, msg)
+			} else {
+				result.Warnings = append(result.Warnings, msg)
+			}
+		}
+	}
+
+	// A chain cut short by an error has no final URL to check; the error is
+	// reported instead.
+	if scheme := FinalScheme(result); opts.RequireFinalScheme != "" && result.Err == nil && scheme != "" && !strings.EqualFold(scheme, opts.RequireFinalScheme) {
+		result.Failures = append(result.Failures, fmt.Sprintf("final URL %s uses %s, not the required %s", result.Final().URL, scheme, opts.RequireFinalScheme))
+	}
+
+	if final := result.Final(); final != nil && opts.FailOnStatus.Contains(final.StatusCode) {
+		result.Failures = append(result.Failures, fmt.Sprintf("final status %d is a failure status", final.StatusCode))
+	}
+
+	var schemeChanges []string
+	for i := 1; i < len(result.Hops); i++ {
+		// A data: URL has no scheme, host or path to compare.
+		if result.Hops[i].DataURI != nil {
+			continue
+		}
+
+		prev, err := url.Parse(result.Hops[i-1].URL)
+		if err != nil {
+			continue
+		}
+		next, err := url.Parse(result.Hops[i].URL)
+		if err != nil {
+			continue
+		}
+
+		result.TrackingParamsAdded = append(result.TrackingParamsAdded, addedParams(prev, next, i)...)
+
+		if isTrailingSlashChange(prev, next) {
+			result.Hops[i].Labels = append(result.Hops[i].Labels, LabelTrailingSlash)
+		}
+
+		for _, transition := range classifyTransition(prev, next) {
+			result.Hops[i].Labels = append(result.Hops[i].Labels, transition)
+			if transition == LabelSchemeChange {
+				schemeChanges = append(schemeChanges, next.Scheme)
+			}
+			for _, fail := range opts.FailOnTransitions {
+				if fail == transition {
+					result.Failures = append(result.Failures, fmt.Sprintf("hop %d was reached by a %s redirect", i, transition))
+				}
+			}
+		}
+	}
+
+	if opts.WarnOnExternalDomain || opts.FailOnExternalDomain {
+		if msg := externalDomain(result); msg != "" {
+			if opts.FailOnExternalDomain {
+				result.Failures = append(result.Failures, msg)
+			} else {
+				result.Warnings = append(result.Warnings, msg)
+			}
+		}
+	}
+
+	// A single upgrade to https is expected, changing back and forth is not.
+	if len(schemeChanges) > 1 && (opts.WarnOnSchemeFlapping || opts.FailOnSchemeFlapping) {
+		first, _ := url.Parse(result.Hops[0].URL)
+		msg := fmt.Sprintf("scheme changed %d times: %s -> %s", len(schemeChanges), first.Scheme, strings.Join(schemeChanges, " -> "))
+		if opts.FailOnSchemeFlapping {
+			result.Failures = append(result.Failures, msg)
+		} else {
+			result.Warnings = append(result.Warnings, msg)
+		}
+	}
+}
+
+// externalDomain describes the first hop of result which left the registered
+// domain of the first hop, or returns "" when none did.
+func externalDomain(result *TraceResult) string {
+	if len(result.Hops) == 0 {
+		return ""
+	}
+	origin := domainOf(&result.Hops[0])
+	for i := 1; i < len(result.Hops); i++ {
+		hop := &result.Hops[i]
+		if hop.DataURI != nil {
+			continue
+		}
+		if domain := domainOf(hop); domain != origin {
+			return fmt.Sprintf("hop %d leaves %s for %s (%s)", i, origin, domain, hop.URL)
+		}
+	}
+	return ""
+}
+
+// domainOf returns the registered domain of hop, or its lower cased host
+// name when it has none.
+func domainOf(hop *Hop) string {
+	if hop.RegisteredDomain != "" {
+		return hop.RegisteredDomain
+	}
+	host := hop.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// knownTrackingParams are query parameters set by common analytics and
+// advertising platforms. Any parameter starting with utm_ is also known.
+var knownTrackingParams = map[string]bool{
+	"fbclid":    true,
+	"gclid":     true,
+	"dclid":     true,
+	"gbraid":    true,
+	"wbraid":    true,
+	"msclkid":   true,
+	"yclid":     true,
+	"twclid":    true,
+	"ttclid":    true,
+	"li_fat_id": true,
+	"igshid":    true,
+	"mc_cid":    true,
+	"mc_eid":    true,
+	"_ga":       true,
+	"_gl":       true,
+	"mkt_tok":   true,
+}
+
+// addedParams returns the query parameters of next, reached at hop, which
+// prev did not have, in the order they appear.
+func addedParams(prev, next *url.URL, hop int) []TrackingParam {
+	before := prev.Query()
+
+	var added []TrackingParam
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(next.RawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, ok := before[name]; ok {
+			continue
+		}
+
+		lower := strings.ToLower(name)
+		added = append(added, TrackingParam{
+			Name:  name,
+			Hop:   hop,
+			Known: knownTrackingParams[lower] || strings.HasPrefix(lower, "utm_"),
+		})
+	}
+	return added
+}
+
+// classifyTransition returns the transition labels describing a redirect from
+// prev to next. It returns nil for a redirect to the identical URL.
+func classifyTransition(prev, next *url.URL) []string {
+	if prev.String() == next.String() {
+		return nil
+	}
+
+	var labels []string
+	if !strings.EqualFold(prev.Scheme, next.Scheme) {
+		labels = append(labels, LabelSchemeChange)
+	}
+	if normalHost(prev) != normalHost(next) {
+		labels = append(labels, LabelHostChange)
+	}
+	if normalPath(prev.Path) != normalPath(next.Path) {
+		labels = append(labels, LabelPathChange)
+	}
+	if prev.Query().Encode() != next.Query().Encode() {
+		labels = append(labels, LabelQueryChange)
+	}
+
+	if labels == nil {
+		return []string{LabelNormalization}
+	}
+	return labels
+}
+
+// normalHost returns the lower cased host of u without its scheme's default
+// port.
+func normalHost(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	switch {
+	case port == "",
+		port == "80" && strings.EqualFold(u.Scheme, "http"),
+		port == "443" && strings.EqualFold(u.Scheme, "https"):
+		return host
+	}
+	return host + ":" + port
+}
+
+// normalPath returns p with dot segments and any trailing slash removed.
+func normalPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return path.Clean(p)
+}
+
+// isTrailingSlashChange reports whether prev and next are identical apart from
+// a trailing slash on the path.
+func isTrailingSlashChange(prev, next *url.URL) bool {
+	if prev.Scheme != next.Scheme || prev.Host != next.Host || prev.RawQuery != next.RawQuery {
+		return false
+	}
+	if prev.Path == next.Path {
+		return false
+	}
+	return strings.TrimSuffix(prev.Path, "/") == strings.TrimSuffix(next.Path, "/")
+}
@@ -0,0 +1,93 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultMaxBodySize is the number of bytes of a response body read when
+// Options.MaxBodySize is unset.
+const DefaultMaxBodySize = 1 << 20
+
+// needsBody reports whether any option inspects the final response body.
+func (t *Tracer) needsBody() bool {
+	return t.opts.BodyRegex != nil || t.opts.CanonicalCheck
+}
+
+// readBody reads at most Options.MaxBodySize bytes of resp's body. The body
+// is decompressed when it was requested with an Accept-Encoding header, such
+// as by BrowserHeader, which stops net/http from decompressing it.
+func (t *Tracer) readBody(resp *http.Response) ([]byte, error) {
+	limit := t.opts.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+
+	var r io.Reader = resp.Body
+	switch encoding := strings.ToLower(resp.Header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing gzip response body: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	case "deflate":
+		zr, err := zlib.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing deflate response body: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("response body is %s encoded, which cannot be decompressed; send a header such as \"Accept-Encoding: gzip\" instead", encoding)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, limit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	return body, nil
+}
+
+// inspectBody applies the body options to the final response body, which was
+// fetched from finalURL.
+func (t *Tracer) inspectBody(result *TraceResult, finalURL *url.URL, body []byte) {
+	if t.opts.CanonicalCheck {
+		result.Canonical = findCanonical(body, finalURL)
+		if result.Canonical != "" && result.Canonical != finalURL.String() {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("canonical URL %s does not match final URL %s", result.Canonical, finalURL))
+		}
+	}
+
+	if re := t.opts.BodyRegex; re != nil {
+		if match := re.FindSubmatch(body); match != nil {
+			// Report the first capture group, or the whole match without one.
+			value := match[0]
+			if len(match) > 1 {
+				value = match[1]
+			}
+			result.BodyMatch = string(value)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// browserProfiles are the headers sent by Chrome 130 and Firefox 131 on
+// Windows when a user navigates to a URL, except that Accept-Encoding leaves
+// out br and zstd, which readBody cannot decompress.
+var browserProfiles = map[string][][2]string{
+	"chrome": {
+		{"Sec-Ch-Ua", `"Chromium";v="130", "Google Chrome";v="130", "Not?A_Brand";v="99"`},
+		{"Sec-Ch-Ua-Mobile", "?0"},
+		{"Sec-Ch-Ua-Platform", `"Windows"`},
+		{"Upgrade-Insecure-Requests", "1"},
+		{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36"},
+		{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+		{"Sec-Fetch-Site", "none"},
+		{"Sec-Fetch-Mode", "navigate"},
+		{"Sec-Fetch-User", "?1"},
+		{"Sec-Fetch-Dest", "document"},
+		{"Accept-Encoding", "gzip, deflate"},
+		{"Accept-Language", "en-US,en;q=0.9"},
+	},
+	"firefox": {
+		{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0"},
+		{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+		{"Accept-Language", "en-US,en;q=0.5"},
+		{"Accept-Encoding", "gzip, deflate"},
+		{"Upgrade-Insecure-Requests", "1"},
+		{"Sec-Fetch-Dest", "document"},
+		{"Sec-Fetch-Mode", "navigate"},
+		{"Sec-Fetch-Site", "none"},
+		{"Sec-Fetch-User", "?1"},
+	},
+}
+
+// BrowserHeader returns the headers the named browser, chrome or firefox,
+// sends when navigating to a page, for use as Options.Header so that servers
+// which treat bots differently respond as they would to the browser.
+func BrowserHeader(name string) (http.Header, error) {
+	profile, ok := browserProfiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown browser %q, expected %s", name, strings.Join(BrowserNames(), " or "))
+	}
+
+	header := http.Header{}
+	for _, h := range profile {
+		header.Set(h[0], h[1])
+	}
+	return header, nil
+}
+
+// BrowserNames returns the browsers BrowserHeader knows, sorted.
+func BrowserNames() []string {
+	names := make([]string, 0, len(browserProfiles))
+	for name := range browserProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
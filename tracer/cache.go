@@ -0,0 +1,180 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCachedBodySize is the largest response body Cache will store.
+const maxCachedBodySize = DefaultMaxBodySize
+
+// Cache stores responses on disk so that repeated traces can replay a chain
+// without network access. Only GET and HEAD responses which are explicitly
+// fresh, through Cache-Control max-age or an Expires header, are stored.
+// Vary is not honored.
+type Cache struct {
+	dir string
+}
+
+// NewCache returns a Cache storing responses in dir, creating it if needed.
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %v", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Clear removes every response stored in the cache.
+func (c *Cache) Clear() error {
+	entries, err := filepath.Glob(filepath.Join(c.dir, "*.resp"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.Remove(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".resp")
+}
+
+// get returns the stored response for req, or nil when there is no fresh
+// entry. Stale entries are removed.
+func (c *Cache) get(req *http.Request) *http.Response {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil
+	}
+
+	path := c.path(req)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil
+	}
+	expires, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+	if err != nil || time.Now().UnixNano() >= expires {
+		os.Remove(path)
+		return nil
+	}
+
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil
+	}
+
+	// Read the body now as the file is closed on return.
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp
+}
+
+// put stores resp when it is fresh, leaving its body readable by the caller.
+func (c *Cache) put(req *http.Request, resp *http.Response) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return
+	}
+
+	expires, ok := freshUntil(resp)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+	if err != nil || len(body) > maxCachedBodySize {
+		// Hand back what was read followed by the remainder, uncached.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// DumpResponse consumes and then restores resp.Body.
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d\n", expires.UnixNano())
+	buf.Write(dump)
+	os.WriteFile(c.path(req), buf.Bytes(), 0o644)
+}
+
+// freshUntil returns when resp stops being fresh according to its
+// Cache-Control and Expires headers. ok is false when it must not be cached.
+func freshUntil(resp *http.Response) (expires time.Time, ok bool) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		date = time.Now()
+	}
+
+	// Every directive is read before max-age is honored, as no-store and the
+	// others forbid caching wherever they appear.
+	maxAge := ""
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.ToLower(strings.TrimSpace(directive)), "=")
+		switch name {
+		case "no-store", "no-cache", "private":
+			return time.Time{}, false
+		case "max-age":
+			if maxAge == "" {
+				maxAge = value
+			}
+		}
+	}
+	if maxAge != "" {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds <= 0 {
+			return time.Time{}, false
+		}
+		return date.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if expires, err := http.ParseTime(resp.Header.Get("Expires")); err == nil && expires.After(date) {
+		return expires, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,52 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFreshUntil(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		cacheControl string
+		ok           bool
+	}{
+		{"max-age=60", true},
+		{"public, max-age=60", true},
+		{"max-age=60, no-store", false},
+		{"no-store, max-age=60", false},
+		{"max-age=60, no-cache", false},
+		{"max-age=60, private", false},
+		{`max-age=60, no-cache="Set-Cookie"`, false},
+		{"max-age=0", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Date", date.Format(http.TimeFormat))
+		resp.Header.Set("Cache-Control", tt.cacheControl)
+
+		expires, ok := freshUntil(resp)
+		if ok != tt.ok {
+			t.Errorf("Cache-Control %q: cacheable = %v, want %v", tt.cacheControl, ok, tt.ok)
+		}
+		if ok && !expires.Equal(date.Add(time.Minute)) {
+			t.Errorf("Cache-Control %q: fresh until %s, want %s", tt.cacheControl, expires, date.Add(time.Minute))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// DefaultCertExpiryWarn is the expiry window used when
+// Options.FailOnCertExpiry is set without Options.CertExpiryWarn.
+const DefaultCertExpiryWarn = 30 * 24 * time.Hour
+
+// Certificate describes the leaf certificate an https hop presented.
+type Certificate struct {
+	// SANs are the DNS names and IP addresses the certificate covers.
+	SANs []string `json:"sans"`
+
+	// NotAfter is when the certificate expires.
+	NotAfter time.Time `json:"not_after"`
+
+	// DaysRemaining is the number of whole days until NotAfter when the hop
+	// was traced, negative once the certificate has expired.
+	DaysRemaining int `json:"days_remaining"`
+
+	// HostMismatch is set when the hop's host is not covered by the
+	// certificate, which only happens when verification is skipped.
+	HostMismatch bool `json:"host_mismatch,omitempty"`
+}
+
+// newCertificate describes the leaf certificate of state for a request to
+// host, or returns nil when there is none.
+func newCertificate(state *tls.ConnectionState, host string) *Certificate {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := state.PeerCertificates[0]
+
+	cert := &Certificate{
+		SANs:     append([]string(nil), leaf.DNSNames...),
+		NotAfter: leaf.NotAfter,
+	}
+	cert.DaysRemaining = int(time.Until(leaf.NotAfter) / (24 * time.Hour))
+	for _, ip := range leaf.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	cert.HostMismatch = leaf.VerifyHostname(host) != nil
+	return cert
+}
@@ -0,0 +1,199 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// maxCNAMEs bounds how many CNAME records are followed for one host, as
+// resolvers do, so that a CNAME loop cannot be followed forever.
+const maxCNAMEs = 16
+
+// cnameTimeout bounds each CNAME chain lookup.
+const cnameTimeout = 5 * time.Second
+
+// lookupCNAMEs returns the names host's CNAME records lead through, in order.
+// With a server, it is asked for host's A records directly, which yields the
+// whole chain. Otherwise resolver, or net.DefaultResolver when nil, is used,
+// which works wherever Go resolves names but only reports the last name of
+// the chain. It returns nil when host is an IP address, has no CNAME or
+// cannot be resolved.
+func lookupCNAMEs(ctx context.Context, resolver *net.Resolver, server, host string) []string {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cnameTimeout)
+	defer cancel()
+
+	if server == "" {
+		return lookupCanonical(ctx, resolver, host)
+	}
+
+	var chain []string
+	name := strings.TrimSuffix(host, ".")
+	// A resolver normally answers with the whole chain at once, but one
+	// which stops part way is asked again for the last name it gave.
+	for len(chain) < maxCNAMEs {
+		answers, err := queryA(ctx, server, name)
+		if err != nil {
+			break
+		}
+		next := followCNAMEs(answers, name, maxCNAMEs-len(chain))
+		if len(next) == 0 {
+			break
+		}
+		chain = append(chain, next...)
+		name = next[len(next)-1]
+		if hasA(answers, name) {
+			break
+		}
+	}
+	return chain
+}
+
+// lookupCanonical returns host's canonical name as the only name of its chain,
+// or nil when it has none.
+func lookupCanonical(ctx context.Context, resolver *net.Resolver, host string) []string {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	cname, err := resolver.LookupCNAME(ctx, host)
+	if err != nil {
+		return nil
+	}
+	name := strings.TrimSuffix(cname, ".")
+	if name == "" || strings.EqualFold(name, strings.TrimSuffix(host, ".")) {
+		return nil
+	}
+	return []string{name}
+}
+
+// followCNAMEs follows the CNAME records among answers from name, returning
+// at most limit targets.
+func followCNAMEs(answers []dnsmessage.Resource, name string, limit int) []string {
+	var chain []string
+	for len(chain) < limit {
+		found := false
+		for _, answer := range answers {
+			cname, ok := answer.Body.(*dnsmessage.CNAMEResource)
+			if !ok || !strings.EqualFold(strings.TrimSuffix(answer.Header.Name.String(), "."), name) {
+				continue
+			}
+			name = strings.TrimSuffix(cname.CNAME.String(), ".")
+			chain = append(chain, name)
+			found = true
+			break
+		}
+		if !found {
+			break
+		}
+	}
+	return chain
+}
+
+// hasA reports whether answers hold an A record for name.
+func hasA(answers []dnsmessage.Resource, name string) bool {
+	for _, answer := range answers {
+		if answer.Header.Type == dnsmessage.TypeA && strings.EqualFold(strings.TrimSuffix(answer.Header.Name.String(), "."), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryA sends a recursive query for the A records of name to server over
+// UDP, retrying over TCP when the answer is truncated, and returns the answer
+// section.
+func queryA(ctx context.Context, server, name string) ([]dnsmessage.Resource, error) {
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return nil, err
+	}
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := exchangeDNS(ctx, "udp", server, packed)
+	if err == nil && resp.Header.Truncated {
+		resp, err = exchangeDNS(ctx, "tcp", server, packed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Header.ID != id {
+		return nil, errors.New("DNS response ID does not match the query")
+	}
+	return resp.Answers, nil
+}
+
+// exchangeDNS sends the packed query to server over network and parses the
+// response.
+func exchangeDNS(ctx context.Context, network, server string, packed []byte) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	buf := make([]byte, 65535)
+	var n int
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(packed)))
+		if _, err := conn.Write(append(framed, packed...)); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(buf[:2]))
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(packed); err != nil {
+			return nil, err
+		}
+		if n, err = conn.Read(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf[:n]); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,110 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// cnameStub is a UDP DNS server answering every query for www.a.test with
+// the chain www.a.test -> a.cdn.test -> edge.pop.test, and an A record for
+// edge.pop.test when A records were asked for.
+func cnameStub(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	chain := []string{"www.a.test.", "a.cdn.test.", "edge.pop.test."}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.Header.ID, Response: true, RecursionAvailable: true},
+				Questions: query.Questions,
+			}
+			if strings.EqualFold(q.Name.String(), chain[0]) {
+				for i := 0; i+1 < len(chain); i++ {
+					resp.Answers = append(resp.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(chain[i]), Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(chain[i+1])},
+					})
+				}
+				if q.Type == dnsmessage.TypeA {
+					resp.Answers = append(resp.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(chain[len(chain)-1]), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+					})
+				}
+			} else {
+				resp.Header.RCode = dnsmessage.RCodeNameError
+			}
+			packed, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestLookupCNAMEs(t *testing.T) {
+	server := cnameStub(t)
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", server)
+		},
+	}
+
+	tests := []struct {
+		name     string
+		resolver *net.Resolver
+		server   string
+		host     string
+		want     []string
+	}{
+		{"server gives the whole chain", nil, server, "www.a.test", []string{"a.cdn.test", "edge.pop.test"}},
+		{"resolver gives the canonical name", resolver, "", "www.a.test", []string{"edge.pop.test"}},
+		{"IP address", resolver, "", "127.0.0.1", nil},
+		{"unknown host", nil, server, "missing.test", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lookupCNAMEs(context.Background(), tt.resolver, tt.server, tt.host)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookupCNAMEs(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
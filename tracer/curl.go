@@ -0,0 +1,53 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// CurlCommand returns a curl command line which repeats req without following
+// redirects, so that a single hop can be reproduced. Request bodies are not
+// recorded and so are not included.
+func CurlCommand(req *SentRequest) string {
+	args := []string{"curl"}
+	if req.Method != "" && req.Method != http.MethodGet {
+		if req.Method == http.MethodHead {
+			args = append(args, "--head")
+		} else {
+			args = append(args, "-X", shellQuote(req.Method))
+		}
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+
+	return strings.Join(append(args, shellQuote(req.URL)), " ")
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
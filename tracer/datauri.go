@@ -0,0 +1,92 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// DataURI describes the payload of a data: URL which a redirect led to when
+// Options.DecodeDataURI is set.
+type DataURI struct {
+	// MediaType is the declared media type, text/plain;charset=US-ASCII
+	// when the URL declares none.
+	MediaType string `json:"media_type"`
+
+	// Size is the number of decoded bytes, counting at most MaxBodySize.
+	Size int64 `json:"size"`
+
+	// Truncated is set when the payload is larger than MaxBodySize.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// decodeDataURI decodes a data: URL of the form
+// data:[<mediatype>][;base64],<data>, reading at most limit bytes of the
+// payload.
+func decodeDataURI(raw string, limit int64) (*DataURI, error) {
+	rest, ok := strings.CutPrefix(raw, "data:")
+	if !ok {
+		if len(raw) < 5 || !strings.EqualFold(raw[:5], "data:") {
+			return nil, fmt.Errorf("not a data URI")
+		}
+		rest = raw[5:]
+	}
+
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil, fmt.Errorf("data URI has no comma separating its data")
+	}
+
+	isBase64 := false
+	if m, found := strings.CutSuffix(meta, ";base64"); found {
+		meta, isBase64 = m, true
+	}
+	if meta == "" {
+		meta = "text/plain;charset=US-ASCII"
+	} else if strings.HasPrefix(meta, ";") {
+		meta = "text/plain" + meta
+	}
+
+	payload, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data URI escaping: %v", err)
+	}
+
+	var r io.Reader = strings.NewReader(payload)
+	if isBase64 {
+		r = base64.NewDecoder(base64.StdEncoding, strings.NewReader(strings.TrimRight(payload, "=")+padding(payload)))
+	}
+
+	n, err := io.Copy(io.Discard, io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 in data URI: %v", err)
+	}
+
+	d := &DataURI{MediaType: meta, Size: n}
+	if n > limit {
+		d.Size, d.Truncated = limit, true
+	}
+	return d, nil
+}
+
+// padding returns the "=" characters missing from unpadded base64 s.
+func padding(s string) string {
+	s = strings.TrimRight(s, "=")
+	return strings.Repeat("=", (4-len(s)%4)%4)
+}
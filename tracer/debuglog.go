@@ -0,0 +1,61 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// checkRedirect decides, through redirectPolicy, whether the client follows
+// the redirect to req, writing the decision to Options.DebugLog.
+func (t *Tracer) checkRedirect(req *http.Request, via []*http.Request) error {
+	err := t.redirectPolicy(req, via)
+	if t.opts.DebugLog == nil {
+		return err
+	}
+
+	attrs := []any{"from", via[len(via)-1].URL.Redacted(), "to", req.URL.Redacted(), "hop", len(via) - 1}
+	switch {
+	case err == nil:
+		t.opts.DebugLog.Debug("redirect followed", attrs...)
+	case errors.Is(err, http.ErrUseLastResponse):
+		var reason string
+		if rec := recorderFrom(req.Context()); rec != nil {
+			reason = rec.stopReason
+		}
+		t.opts.DebugLog.Debug("redirect not followed", append(attrs, "reason", reason)...)
+	default:
+		t.opts.DebugLog.Debug("redirect aborted the trace", append(attrs, "error", err)...)
+	}
+	return err
+}
+
+// logRequest writes req, as it is about to be sent, to log.
+func logRequest(log *slog.Logger, req *http.Request, reveal bool) {
+	sent := newSentRequest(req, reveal)
+	log.Debug("request", "method", sent.Method, "url", sent.URL, "header", sent.Header, "content_length", req.ContentLength)
+}
+
+// logResponse writes the outcome of the round trip for req to log.
+func logResponse(log *slog.Logger, req *http.Request, resp *http.Response, err error, source string) {
+	if err != nil {
+		log.Debug("round trip failed", "url", req.URL.Redacted(), "error", err)
+		return
+	}
+	log.Debug("response", "url", req.URL.Redacted(), "source", source, "status", resp.StatusCode, "proto", resp.Proto,
+		"header", resp.Header, "content_length", resp.ContentLength)
+}
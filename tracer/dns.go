@@ -0,0 +1,49 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// newDialer returns the dialer used for outbound connections. When dnsServer
+// is set, host names are resolved by querying it rather than the system
+// resolver.
+func newDialer(dnsServer string) (*net.Dialer, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if dnsServer == "" {
+		return dialer, nil
+	}
+
+	if _, _, err := net.SplitHostPort(dnsServer); err != nil {
+		return nil, fmt.Errorf("invalid DNS server %q, expected host:port: %v", dnsServer, err)
+	}
+
+	dialer.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, dnsServer)
+		},
+	}
+	return dialer, nil
+}
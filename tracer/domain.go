@@ -0,0 +1,106 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// registeredDomain returns the registered domain (eTLD+1) of host, such as
+// example.co.uk for www.a.example.co.uk, or "" for an IP address or a host
+// which is itself a public suffix.
+func registeredDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return ""
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// domainGroup counts the hops and chains which reached a registered domain.
+type domainGroup struct {
+	domain string
+	hops   int
+	inputs []string
+}
+
+// domainGroups collects results by the registered domains of their hops.
+type domainGroups struct {
+	groups map[string]*domainGroup
+}
+
+func (g *domainGroups) add(result *TraceResult) {
+	if g.groups == nil {
+		g.groups = map[string]*domainGroup{}
+	}
+
+	seen := map[string]bool{}
+	for _, hop := range result.Hops {
+		domain := hop.RegisteredDomain
+		if domain == "" {
+			domain = "(no registered domain)"
+		}
+		group, ok := g.groups[domain]
+		if !ok {
+			group = &domainGroup{domain: domain}
+			g.groups[domain] = group
+		}
+		group.hops++
+		if !seen[domain] {
+			seen[domain] = true
+			group.inputs = append(group.inputs, result.InputURL)
+		}
+	}
+}
+
+// write lists each domain, most visited first, with the input URLs whose
+// chains reached it.
+func (g *domainGroups) write(w io.Writer) error {
+	groups := make([]*domainGroup, 0, len(g.groups))
+	for _, group := range g.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].inputs) != len(groups[j].inputs) {
+			return len(groups[i].inputs) > len(groups[j].inputs)
+		}
+		return groups[i].domain < groups[j].domain
+	})
+
+	if _, err := fmt.Fprintln(w, "Registered Domains:"); err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if _, err := fmt.Fprintf(w, "  %s: %d hop(s) in %d chain(s)\n", group.domain, group.hops, len(group.inputs)); err != nil {
+			return err
+		}
+		for _, input := range group.inputs {
+			if _, err := fmt.Fprintf(w, "    %s\n", input); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dump is a directory of recorded responses. Options.Dump writes every
+// response a trace receives into it, and Options.Replay answers requests from
+// it instead of the network, so that a captured chain can be studied offline
+// without contacting its hosts again. Each capture of a request is kept in a
+// file of its own, beginning with a line naming the request followed by the
+// response as sent.
+type Dump struct {
+	dir string
+
+	// Since and Until, when set, limit replay to responses captured within
+	// that window. The newest capture inside it is replayed.
+	Since, Until time.Time
+}
+
+// NewDump returns a Dump using dir, creating it if needed.
+func NewDump(dir string) (*Dump, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating dump directory: %v", err)
+	}
+	return &Dump{dir: dir}, nil
+}
+
+func (d *Dump) prefix(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
+
+// record stores resp, leaving its body readable by the caller. At most
+// maxCachedBodySize bytes of the body are stored.
+func (d *Dump) record(req *http.Request, resp *http.Response) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize))
+	if err != nil {
+		return
+	}
+	rest := resp.Body
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// DumpResponse consumes and then restores resp.Body.
+	dump, err := httputil.DumpResponse(resp, true)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), rest), rest}
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\n", req.Method, req.URL)
+	buf.Write(dump)
+	path := fmt.Sprintf("%s-%d.resp", d.prefix(req), time.Now().UnixNano())
+	os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// replay returns the newest recorded response to req within the window.
+func (d *Dump) replay(req *http.Request) (*http.Response, error) {
+	prefix := d.prefix(req)
+	paths, err := filepath.Glob(prefix + "-*.resp")
+	if err != nil {
+		return nil, err
+	}
+
+	var newest string
+	var newestAt time.Time
+	for _, path := range paths {
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(path, prefix+"-"), ".resp"), 10, 64)
+		if err != nil {
+			continue
+		}
+		at := time.Unix(0, nanos)
+		if (!d.Since.IsZero() && at.Before(d.Since)) || (!d.Until.IsZero() && at.After(d.Until)) {
+			continue
+		}
+		if newest == "" || at.After(newestAt) {
+			newest, newestAt = path, at
+		}
+	}
+	if newest == "" {
+		return nil, fmt.Errorf("no response to %s %s was dumped in %s", req.Method, req.URL, d.window())
+	}
+
+	data, err := os.ReadFile(newest)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(bytes.NewReader(data))
+	if _, err := r.ReadString('\n'); err != nil {
+		return nil, fmt.Errorf("error reading dumped response %s: %v", newest, err)
+	}
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading dumped response %s: %v", newest, err)
+	}
+	return resp, nil
+}
+
+// window describes where replay looks for responses.
+func (d *Dump) window() string {
+	switch {
+	case !d.Since.IsZero() && !d.Until.IsZero():
+		return fmt.Sprintf("%s between %s and %s", d.dir, d.Since.Format(time.RFC3339), d.Until.Format(time.RFC3339))
+	case !d.Since.IsZero():
+		return fmt.Sprintf("%s since %s", d.dir, d.Since.Format(time.RFC3339))
+	case !d.Until.IsZero():
+		return fmt.Sprintf("%s until %s", d.dir, d.Until.Format(time.RFC3339))
+	}
+	return d.dir
+}
@@ -0,0 +1,257 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HARReporter collects every hop into an HTTP Archive (HAR 1.2) document,
+// which browser devtools and HAR viewers can load. Each traced URL becomes a
+// page whose entries are its hops. The document is written by Flush.
+//
+// Request headers need Options.CaptureRequests, response headers
+// Options.CaptureHeaders and phase timings Options.Timing; without them the
+// entries carry only what is known.
+type HARReporter struct {
+	w       io.Writer
+	pages   []harPage
+	entries []harEntry
+}
+
+// NewHARReporter returns a HARReporter which writes to w.
+func NewHARReporter(w io.Writer) *HARReporter {
+	return &HARReporter{w: w, pages: []harPage{}, entries: []harEntry{}}
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Pages   []harPage  `json:"pages"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     harPageTimings `json:"pageTimings"`
+}
+
+type harPageTimings struct {
+	OnLoad float64 `json:"onLoad"`
+}
+
+type harEntry struct {
+	PageRef         string      `json:"pageref"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+}
+
+type harRequest struct {
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	HTTPVersion string    `json:"httpVersion"`
+	Cookies     []harPair `json:"cookies"`
+	Headers     []harPair `json:"headers"`
+	QueryString []harPair `json:"queryString"`
+	HeadersSize int       `json:"headersSize"`
+	BodySize    int       `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harTimings are in milliseconds, -1 marking a phase which did not happen or
+// was not measured.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Report adds a page for result and an entry for each of its hops which
+// received a response.
+func (r *HARReporter) Report(result *TraceResult) error {
+	page := harPage{
+		StartedDateTime: harTime(time.Now()),
+		ID:              fmt.Sprintf("page_%d", len(r.pages)+1),
+		Title:           result.InputURL,
+	}
+
+	for i, hop := range result.Hops {
+		// A data: URL or WebSocket endpoint which was not contacted has no
+		// response to describe.
+		if hop.StatusCode == 0 {
+			continue
+		}
+
+		entry := newHAREntry(&hop)
+		entry.PageRef = page.ID
+		if i == 0 {
+			page.StartedDateTime = entry.StartedDateTime
+		}
+		page.PageTimings.OnLoad += entry.Time
+		r.entries = append(r.entries, entry)
+	}
+
+	r.pages = append(r.pages, page)
+	return nil
+}
+
+// Flush writes the HAR document holding every result reported so far.
+func (r *HARReporter) Flush() error {
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Log harLog `json:"log"`
+	}{harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "urltrace", Version: "1.0"},
+		Pages:   r.pages,
+		Entries: r.entries,
+	}})
+}
+
+// newHAREntry describes hop as a HAR entry.
+func newHAREntry(hop *Hop) harEntry {
+	started := time.Now()
+	timings := harTimings{Blocked: -1, DNS: -1, Connect: -1, Wait: milliseconds(hop.Duration), SSL: -1}
+	if t := hop.Timing; t != nil {
+		started = t.Started
+		timings.DNS = optionalMilliseconds(t.DNS)
+		// HAR counts the TLS handshake as part of connecting.
+		timings.Connect = optionalMilliseconds(t.Connect + t.TLS)
+		timings.SSL = optionalMilliseconds(t.TLS)
+		timings.Wait = milliseconds(t.TTFB)
+	}
+
+	entry := harEntry{
+		StartedDateTime: harTime(started),
+		Timings:         timings,
+		Request: harRequest{
+			Method:      hop.Method,
+			URL:         hop.URL,
+			HTTPVersion: hop.Protocol,
+			Cookies:     []harPair{},
+			Headers:     []harPair{},
+			QueryString: []harPair{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			Status:      hop.StatusCode,
+			StatusText:  http.StatusText(hop.StatusCode),
+			HTTPVersion: hop.Protocol,
+			Cookies:     []harPair{},
+			Headers:     harHeaders(hop.Header),
+			Content:     harContent{MimeType: hop.Header.Get("Content-Type")},
+			RedirectURL: hop.RedirectURL,
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+	for _, d := range []float64{timings.DNS, timings.Connect, timings.Send, timings.Wait, timings.Receive} {
+		if d > 0 {
+			entry.Time += d
+		}
+	}
+
+	if hop.Request != nil {
+		entry.Request.URL = hop.Request.URL
+		entry.Request.Headers = harHeaders(hop.Request.Header)
+	}
+	if u, err := url.Parse(entry.Request.URL); err == nil {
+		entry.Request.QueryString = harHeaders(u.Query())
+	}
+	if len(hop.ResolvedAddrs) > 0 {
+		entry.ServerIPAddress = hop.ResolvedAddrs[0]
+	}
+	return entry
+}
+
+// harHeaders flattens h into name/value pairs sorted by name.
+func harHeaders(h map[string][]string) []harPair {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []harPair{}
+	for _, name := range names {
+		for _, value := range h[name] {
+			pairs = append(pairs, harPair{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+// harTime formats t as the ISO 8601 timestamp HAR expects.
+func harTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// optionalMilliseconds is like milliseconds but marks a phase which took no
+// time as not having happened.
+func optionalMilliseconds(d time.Duration) float64 {
+	if d == 0 {
+		return -1
+	}
+	return milliseconds(d)
+}
@@ -0,0 +1,66 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ChainHash returns the hex encoded SHA-256 of result's chain, so that a
+// stored hash can cheaply reveal when a URL's redirects change.
+//
+// The hash covers one line per hop, in order, holding the hop's URL with its
+// scheme and host lower cased, internationalized hosts in punycode, any
+// default port and fragment removed, and the
+// path and query left untouched. When withStatus is set each line is prefixed
+// with the hop's status and a space. A trace which ended in an error adds a
+// final "error" line, without the message as it may vary between runs. Each
+// line ends with "\n". Timings, headers and labels are not included.
+func ChainHash(result *TraceResult, withStatus bool) string {
+	h := sha256.New()
+	for _, hop := range result.Hops {
+		if withStatus {
+			h.Write([]byte(strconv.Itoa(hop.StatusCode) + " "))
+		}
+		h.Write([]byte(hashURL(hop.URL) + "\n"))
+	}
+	if result.Err != nil {
+		h.Write([]byte("error\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashURL returns raw normalized as described by ChainHash, or raw itself if
+// it cannot be parsed.
+func hashURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	n := *u
+	n.Scheme = strings.ToLower(u.Scheme)
+	n.Host = normalHost(u)
+	if err := toASCIIHost(&n); err == nil {
+		n.Host = strings.ToLower(n.Host)
+	}
+	n.Fragment = ""
+	n.RawFragment = ""
+	return n.String()
+}
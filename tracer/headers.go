@@ -0,0 +1,141 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces credentials in recorded requests.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are the request headers which carry credentials.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// redirectSensitiveHeaders are the headers http.Client drops from a redirect
+// to another domain.
+var redirectSensitiveHeaders = map[string]bool{
+	"Authorization":    true,
+	"Www-Authenticate": true,
+	"Cookie":           true,
+	"Cookie2":          true,
+}
+
+// sendsSensitiveHeaders reports whether headers in redirectSensitiveHeaders
+// given for a chain starting at initial may be sent to dest. As http.Client
+// does, they go only to initial's host and its subdomains.
+func sendsSensitiveHeaders(initial, dest *url.URL) bool {
+	if initial == nil {
+		return true
+	}
+	domain := strings.ToLower(initial.Hostname())
+	host := strings.ToLower(dest.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// followHeader returns the headers for a request to dest which follows prev
+// in a chain starting at initial. They are prev's, without those in
+// redirectSensitiveHeaders when dest may not receive them, since prev already
+// carries the credentials added by TransportWrapper.
+func followHeader(prev *http.Request, initial, dest *url.URL) http.Header {
+	header := prev.Header.Clone()
+	if sendsSensitiveHeaders(initial, dest) {
+		return header
+	}
+	for name := range redirectSensitiveHeaders {
+		header.Del(name)
+	}
+	return header
+}
+
+// SentRequest records the request made for a hop. Headers which net/http adds
+// while writing the request, such as User-Agent and Host, are not included.
+type SentRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"headers,omitempty"`
+}
+
+// newSentRequest records req, redacting credentials unless reveal is set.
+func newSentRequest(req *http.Request, reveal bool) *SentRequest {
+	sent := &SentRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+	if reveal {
+		return sent
+	}
+
+	sent.URL = req.URL.Redacted()
+	for _, name := range sensitiveHeaders {
+		if values, ok := sent.Header[name]; ok {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	return sent
+}
+
+// HeaderChange holds the values of a header present on both hops being
+// compared whose values differ.
+type HeaderChange struct {
+	From []string `json:"from"`
+	To   []string `json:"to"`
+}
+
+// HeaderDiff describes how the response headers of the final hop differ from
+// those of the first hop.
+type HeaderDiff struct {
+	Added   http.Header             `json:"added,omitempty"`
+	Removed http.Header             `json:"removed,omitempty"`
+	Changed map[string]HeaderChange `json:"changed,omitempty"`
+}
+
+// Empty reports whether the diff holds no differences.
+func (d *HeaderDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffHeaders compares the headers of from and to.
+func diffHeaders(from, to http.Header) *HeaderDiff {
+	diff := &HeaderDiff{
+		Added:   http.Header{},
+		Removed: http.Header{},
+		Changed: map[string]HeaderChange{},
+	}
+
+	for name, values := range to {
+		fromValues, ok := from[name]
+		switch {
+		case !ok:
+			diff.Added[name] = values
+		case !reflect.DeepEqual(fromValues, values):
+			diff.Changed[name] = HeaderChange{From: fromValues, To: values}
+		}
+	}
+
+	for name, values := range from {
+		if _, ok := to[name]; !ok {
+			diff.Removed[name] = values
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,57 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// validateHostPatterns returns an error for the first malformed pattern.
+func validateHostPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid host pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchHost returns the first of patterns which matches host, ignoring case.
+func matchHost(host string, patterns []string) (string, bool) {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// checkHost returns a *PolicyError when host is denied, or when an allowlist
+// is given and host is not on it. where introduces host in the reason, such
+// as "redirect from hop 1 is to".
+func checkHost(host, where string, allow, deny []string) error {
+	if pattern, ok := matchHost(host, deny); ok {
+		return &PolicyError{Reason: fmt.Sprintf("%s %s, which is denied by %q", where, host, pattern)}
+	}
+	if len(allow) > 0 {
+		if _, ok := matchHost(host, allow); !ok {
+			return &PolicyError{Reason: fmt.Sprintf("%s %s, which is not an allowed host", where, host)}
+		}
+	}
+	return nil
+}
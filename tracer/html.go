@@ -0,0 +1,102 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// findCanonical returns the href of the first <link rel="canonical"> in body,
+// resolved against base. It returns "" when the document has none.
+func findCanonical(body []byte, base *url.URL) string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var href string
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "link" && hasRel(n, "canonical") {
+			href = attr(n, "href")
+			return href != ""
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	if !walk(doc) {
+		return ""
+	}
+
+	u, err := base.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+// findMetaRefresh returns the content of the first
+// <meta http-equiv="refresh"> in body, or "" when the document has none.
+func findMetaRefresh(body []byte) string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var content string
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "meta" && strings.EqualFold(attr(n, "http-equiv"), "refresh") {
+			content = attr(n, "content")
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(doc)
+	return content
+}
+
+// hasRel reports whether n's space separated rel attribute contains rel.
+func hasRel(n *html.Node, rel string) bool {
+	for _, v := range strings.Fields(attr(n, "rel")) {
+		if strings.EqualFold(v, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// attr returns the value of n's attribute named key.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
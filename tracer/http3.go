@@ -0,0 +1,88 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// http3HandshakeTimeout bounds how long a QUIC handshake may take before a
+// host is assumed not to offer HTTP/3, as servers which do not commonly drop
+// the packets silently.
+const http3HandshakeTimeout = 3 * time.Second
+
+// newHTTP3Transport returns an HTTP/3 round tripper using the TLS settings of
+// base.
+func newHTTP3Transport(base *http.Transport, maxHeaderBytes int64) *http3.Transport {
+	var tlsConfig *tls.Config
+	if base != nil && base.TLSClientConfig != nil {
+		tlsConfig = base.TLSClientConfig.Clone()
+	}
+	return &http3.Transport{
+		TLSClientConfig:        tlsConfig,
+		QUICConfig:             &quic.Config{HandshakeIdleTimeout: http3HandshakeTimeout},
+		MaxResponseHeaderBytes: int(maxHeaderBytes),
+	}
+}
+
+// http3Fallback remembers the hosts which could not be reached over HTTP/3
+// so that later requests to them go straight to TCP.
+type http3Fallback struct {
+	unavailable sync.Map
+}
+
+// roundTrip tries req over h3 when it is an https request to a host not
+// known to lack HTTP/3, falling back to tcp when that fails.
+func (f *http3Fallback) roundTrip(h3, tcp http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if !strings.EqualFold(req.URL.Scheme, "https") {
+		return tcp.RoundTrip(req)
+	}
+	if _, ok := f.unavailable.Load(req.URL.Host); ok {
+		return tcp.RoundTrip(req)
+	}
+
+	resp, err := h3.RoundTrip(req)
+	if err == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+	f.unavailable.Store(req.URL.Host, true)
+
+	// The failed attempt may have consumed the body.
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return tcp.RoundTrip(req)
+}
+
+// protocolOf names the protocol of resp, e.g. HTTP/1.1, HTTP/2 or HTTP/3.
+func protocolOf(resp *http.Response) string {
+	if resp.ProtoMajor >= 2 {
+		return fmt.Sprintf("HTTP/%d", resp.ProtoMajor)
+	}
+	return resp.Proto
+}
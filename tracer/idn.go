@@ -0,0 +1,95 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// toASCIIHost converts an internationalized host name in u to its punycode
+// form, which is what is resolved and sent in the Host header.
+func toASCIIHost(u *url.URL) error {
+	host := u.Hostname()
+	if isASCII(host) {
+		return nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return err
+	}
+	if port := u.Port(); port != "" {
+		ascii = net.JoinHostPort(ascii, port)
+	}
+	u.Host = ascii
+	return nil
+}
+
+// unicodeURL returns raw with a punycode host shown in its Unicode form, or
+// raw itself when there is nothing to convert.
+func unicodeURL(raw string) string {
+	if !strings.Contains(raw, "xn--") {
+		return raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	// url.URL.String would percent-encode a Unicode host, so the host is
+	// replaced in place instead.
+	i := strings.Index(raw, "://")
+	if i == -1 {
+		return raw
+	}
+	authority := raw[i+3:]
+	at := strings.Index(authority, u.Host)
+	if at == -1 {
+		return raw
+	}
+	return raw[:i+3] + authority[:at] + unicodeHost(u.Host) + authority[at+len(u.Host):]
+}
+
+// unicodeHost returns host, which may carry a port, with its punycode labels
+// shown in Unicode.
+func unicodeHost(host string) string {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+
+	unicode, err := idna.Display.ToUnicode(name)
+	if err != nil {
+		return host
+	}
+	if port != "" {
+		return net.JoinHostPort(unicode, port)
+	}
+	return unicode
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Link describes a Link header which led from a hop to the next one when
+// Options.FollowLinkRels is set.
+type Link struct {
+	// Rel is the relation type which matched, such as canonical.
+	Rel string `json:"rel"`
+
+	// URL is the link target resolved against the hop's URL.
+	URL string `json:"url"`
+}
+
+// headerLink is one link of a Link header.
+type headerLink struct {
+	target string
+	rels   []string
+}
+
+// parseLinks parses the links of Link header values as described by RFC 8288
+// (formerly RFC 5988): a comma separated list of <URI> each followed by
+// ;-separated parameters, whose values may be quoted. Parsing of a value stops
+// at the first malformed link.
+func parseLinks(values []string) []headerLink {
+	var links []headerLink
+	for _, value := range values {
+		s := value
+		for {
+			s = strings.TrimLeft(s, " \t,")
+			if !strings.HasPrefix(s, "<") {
+				break
+			}
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				break
+			}
+			link := headerLink{target: strings.TrimSpace(s[1:end])}
+			s = s[end+1:]
+
+			for {
+				s = strings.TrimLeft(s, " \t")
+				if !strings.HasPrefix(s, ";") {
+					break
+				}
+				var name, param string
+				name, param, s = parseLinkParam(s[1:])
+				if strings.EqualFold(name, "rel") {
+					link.rels = append(link.rels, strings.Fields(strings.ToLower(param))...)
+				}
+			}
+			links = append(links, link)
+
+			s = strings.TrimLeft(s, " \t")
+			if !strings.HasPrefix(s, ",") {
+				break
+			}
+		}
+	}
+	return links
+}
+
+// parseLinkParam parses a name=value link parameter at the start of s, after
+// its leading semicolon, returning the rest of s.
+func parseLinkParam(s string) (name, value, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	end := strings.IndexAny(s, "=;,")
+	if end < 0 {
+		return strings.TrimSpace(s), "", ""
+	}
+	name = strings.TrimSpace(s[:end])
+	if s[end] != '=' {
+		return name, "", s[end:]
+	}
+
+	s = strings.TrimLeft(s[end+1:], " \t")
+	if strings.HasPrefix(s, `"`) {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					b.WriteByte(s[i])
+				}
+			case '"':
+				return name, b.String(), s[i+1:]
+			default:
+				b.WriteByte(s[i])
+			}
+		}
+		return name, b.String(), ""
+	}
+
+	end = strings.IndexAny(s, ";,")
+	if end < 0 {
+		return name, strings.TrimSpace(s), ""
+	}
+	return name, strings.TrimSpace(s[:end]), s[end:]
+}
+
+// nextLink returns the request following the first link of resp's Link
+// headers with one of Options.FollowLinkRels, recording it on the last hop of
+// rec. It returns nil when there is no such link, it points back to resp's own
+// URL, as a canonical link often does, or the redirect policy stops the chain
+// here.
+func (t *Tracer) nextLink(ctx context.Context, resp *http.Response, rec *recorder) (*http.Request, error) {
+	if len(t.opts.FollowLinkRels) == 0 || isRedirect(resp.StatusCode) {
+		return nil, nil
+	}
+
+	var rel, target string
+	for _, link := range parseLinks(resp.Header.Values("Link")) {
+		for _, r := range link.rels {
+			for _, want := range t.opts.FollowLinkRels {
+				if rel == "" && strings.EqualFold(r, want) {
+					rel, target = r, link.target
+				}
+			}
+		}
+	}
+	if rel == "" {
+		return nil, nil
+	}
+
+	next, err := resp.Request.URL.Parse(target)
+	if err != nil || next.String() == resp.Request.URL.String() {
+		return nil, nil
+	}
+
+	if len(rec.hops) > 0 {
+		hop := &rec.hops[len(rec.hops)-1]
+		display := next.String()
+		if !t.opts.Punycode {
+			display = unicodeURL(display)
+		}
+		hop.Link = &Link{Rel: rel, URL: display}
+		hop.RedirectURL = display
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, next.String(), nil)
+	if err != nil {
+		return nil, nil
+	}
+	req.Header = followHeader(resp.Request, rec.initial, req.URL)
+	req.Response = resp
+
+	if err := t.checkRedirect(req, viaOf(resp)); err != nil {
+		if errors.Is(err, http.ErrUseLastResponse) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,139 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// LoopError is the error recorded when a redirect returns to a request made
+// earlier in the chain.
+type LoopError struct {
+	// URL is the URL the chain returned to.
+	URL string
+
+	// From and To are the indexes of the hop whose redirect closed the loop
+	// and of the hop it returned to.
+	From, To int
+
+	// CookieDriven is set when the URL repeated but the cookies sent with it
+	// changed on every visit, so it is cookie state which keeps the chain
+	// going. Only a chain traced with Options.EnableCookies can be
+	// cookie-driven.
+	CookieDriven bool
+
+	// Cookies names the cookies whose values changed between the revisits
+	// of a cookie-driven loop.
+	Cookies []string
+}
+
+func (e *LoopError) Error() string {
+	if e.CookieDriven {
+		return fmt.Sprintf("cookie-driven redirect loop: hop %d redirects back to %s (hop %d) with cookies %s changing between visits", e.From, e.URL, e.To, strings.Join(e.Cookies, ", "))
+	}
+	return fmt.Sprintf("redirect loop: hop %d redirects back to %s (hop %d) with the same request", e.From, e.URL, e.To)
+}
+
+// checkLoop returns a *LoopError when req repeats a request in via. With a
+// cookie jar a repeated URL is only a plain loop when the cookies sent are
+// unchanged too; a URL may be revisited once with different cookies, as
+// cookie checks commonly do, but a second revisit is a cookie-driven loop.
+func checkLoop(req *http.Request, via []*http.Request, jar http.CookieJar) error {
+	target := req.URL.String()
+	state := cookieState(req.Header, nil)
+	if jar != nil {
+		state = cookieState(req.Header, jar.Cookies(req.URL))
+	}
+
+	var visits []map[string]string
+	first := -1
+	for i, prev := range via {
+		if prev.Method != req.Method || prev.URL.String() != target {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+
+		prevState := cookieState(prev.Header, nil)
+		if jar == nil || sameCookies(prevState, state) {
+			return &LoopError{URL: target, From: len(via) - 1, To: i}
+		}
+		visits = append(visits, prevState)
+	}
+
+	if len(visits) < 2 {
+		return nil
+	}
+	return &LoopError{
+		URL:          target,
+		From:         len(via) - 1,
+		To:           first,
+		CookieDriven: true,
+		// The first visit often predates any cookies, so only the
+		// revisits say which cookies drive the loop.
+		Cookies: changedCookies(append(visits[1:], state)),
+	}
+}
+
+// cookieState returns the cookies sent by a request with header h, plus
+// those in extra which the client will add, by name.
+func cookieState(h http.Header, extra []*http.Cookie) map[string]string {
+	state := map[string]string{}
+	for _, c := range (&http.Request{Header: h}).Cookies() {
+		state[c.Name] = c.Value
+	}
+	for _, c := range extra {
+		state[c.Name] = c.Value
+	}
+	return state
+}
+
+func sameCookies(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if v, ok := b[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// changedCookies returns, sorted, the names of cookies which are missing
+// from or differ in any of states.
+func changedCookies(states []map[string]string) []string {
+	changed := map[string]bool{}
+	for _, state := range states {
+		for name, value := range state {
+			for _, other := range states {
+				if v, ok := other[name]; !ok || v != value {
+					changed[name] = true
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
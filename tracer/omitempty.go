@@ -0,0 +1,122 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// zeroKeys are the keys whose zero value is meaningful, such as the index of
+// the first hop, and so is kept by omitEmpty.
+var zeroKeys = map[string]bool{
+	"hop":            true,
+	"hop_index":      true,
+	"delay":          true,
+	"days_remaining": true,
+	"size":           true,
+}
+
+// omitEmpty encodes v as JSON without any field whose value is null, false,
+// zero, an empty string or an empty array or object, other than those in
+// zeroKeys. Fields keep their order, and array elements are never removed so
+// that positions such as hop indexes are unchanged.
+func omitEmpty(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out bytes.Buffer
+	if _, err := pruneValue(dec, &out, true); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// pruneValue copies the next value of dec to out with its empty fields
+// removed, reporting whether the value itself is empty. keepZero keeps a
+// zero number or false, for elements of an array and fields in zeroKeys.
+func pruneValue(dec *json.Decoder, out *bytes.Buffer, keepZero bool) (empty bool, err error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		open, close := byte(tok), byte('}')
+		if tok == '[' {
+			close = ']'
+		}
+		out.WriteByte(open)
+		members := 0
+		for dec.More() {
+			var field bytes.Buffer
+			keep := true
+			if open == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return false, err
+				}
+				name, _ := key.(string)
+				encoded, _ := json.Marshal(name)
+				field.Write(encoded)
+				field.WriteByte(':')
+				keep = zeroKeys[name[strings.LastIndexByte(name, '.')+1:]]
+			}
+			empty, err := pruneValue(dec, &field, keep)
+			if err != nil {
+				return false, err
+			}
+			if open == '{' && empty {
+				continue
+			}
+			if members > 0 {
+				out.WriteByte(',')
+			}
+			out.Write(field.Bytes())
+			members++
+		}
+		if _, err := dec.Token(); err != nil {
+			return false, err
+		}
+		out.WriteByte(close)
+		return members == 0, nil
+	case string:
+		encoded, err := json.Marshal(tok)
+		if err != nil {
+			return false, err
+		}
+		out.Write(encoded)
+		return tok == "", nil
+	case json.Number:
+		out.WriteString(tok.String())
+		f, err := tok.Float64()
+		return err == nil && f == 0 && !keepZero, nil
+	case bool:
+		fmt.Fprint(out, tok)
+		return !tok && !keepZero, nil
+	case nil:
+		out.WriteString("null")
+		return true, nil
+	default:
+		return false, fmt.Errorf("unexpected JSON token %v", tok)
+	}
+}
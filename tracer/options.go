@@ -0,0 +1,391 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Proxy rotation strategies for Options.ProxyRotation.
+const (
+	ProxyRoundRobin = "round-robin"
+	ProxyRandom     = "random"
+)
+
+// Options configures a Tracer.
+type Options struct {
+	// Timeout is the timeout applied to each individual request in a chain.
+	Timeout time.Duration
+
+	// ChainTimeout limits the total time spent following a URL's redirect
+	// chain. Zero means no limit beyond Timeout.
+	ChainTimeout time.Duration
+
+	// WorkerTimeout is a hard limit on the time spent on a single input URL,
+	// including retries and repeats. A trace which exceeds it is cancelled
+	// and ends with an *AbandonedError, keeping the hops it completed. The
+	// batch moves on once the cancelled trace has returned, so traces never
+	// overlap. Zero means no limit.
+	WorkerTimeout time.Duration
+
+	// Transport is the transport used to perform requests. When nil,
+	// http.DefaultTransport is used. The transport is cloned before any other
+	// option is applied to it.
+	Transport *http.Transport
+
+	// Proxy routes every request through the given http, https or socks5
+	// proxy. Credentials may be supplied as the URL's user info.
+	Proxy *url.URL
+
+	// Proxies holds a pool of proxies, like Proxy, with one chosen for each
+	// trace according to ProxyRotation. Proxy, when also set, joins the pool.
+	Proxies []*url.URL
+
+	// ProxyRotation selects how a proxy is chosen from the pool: ProxyRoundRobin
+	// (the default) or ProxyRandom.
+	ProxyRotation string
+
+	// ProxyFailover traces the URL again through the following proxies in the
+	// pool when a trace fails with a network error, such as the proxy being
+	// unreachable.
+	ProxyFailover bool
+
+	// FollowStatuses restricts which redirect statuses are followed. A
+	// redirect whose status is not in the set ends the chain with its
+	// response. A nil or empty set follows every redirect status.
+	FollowStatuses *StatusSet
+
+	// StopOnStatus ends the chain at the first response whose status is in
+	// the set, even a redirect, with a StopReason naming the hop.
+	StopOnStatus *StatusSet
+
+	// StrictMethod aborts the chain with a *PolicyError when following a
+	// redirect would change the request method, as http.Client does for a
+	// POST answered with a 301, 302 or 303.
+	StrictMethod bool
+
+	// DNSServer is the host:port of a DNS server used to resolve every host
+	// in the chain in place of the system resolver.
+	DNSServer string
+
+	// ShowDNS looks up every A and AAAA record of each hop's host, recording
+	// them with the address actually connected to.
+	ShowDNS bool
+
+	// ShowCNAME records the chain of CNAME records each hop's host resolves
+	// through by asking DNSServer. Without DNSServer, the system resolver is
+	// used, which only reports the canonical name at the end of the chain.
+	ShowCNAME bool
+
+	// UnixSocket, when set, is the path of a Unix domain socket every
+	// connection is made to in place of the URL's host, which is still used
+	// for the Host header and TLS server name. It cannot be combined with a
+	// proxy.
+	UnixSocket string
+
+	// ConnectVia, when set, is the host:port of an HTTP proxy through which
+	// every connection is tunneled with CONNECT, for http as well as https
+	// URLs, as a bastion or jump host requires. The proxy resolves the
+	// target hosts. It cannot be combined with a proxy, UnixSocket or HTTP3.
+	ConnectVia string
+
+	// FollowMetaRefresh follows a final response's Refresh header or, for
+	// HTML, its <meta http-equiv="refresh"> tag as a further hop, without
+	// waiting for the requested delay. Refreshes count towards the redirect
+	// limit and are subject to the same redirect policy.
+	FollowMetaRefresh bool
+
+	// FollowLinkRels follows a final response's Link header, such as
+	// Link: <https://example.com/a>; rel="canonical", as a further hop when
+	// the link has one of these relation types. A link to the response's own
+	// URL ends the chain. Links count towards the redirect limit and are
+	// subject to the same redirect policy.
+	FollowLinkRels []string
+
+	// DecodeDataURI ends a chain which redirects to a data: URL with a final
+	// hop describing the decoded payload, counting at most MaxBodySize bytes,
+	// rather than an *InvalidURL error.
+	DecodeDataURI bool
+
+	// DebugLog, when set, receives a detailed record of every trace at debug
+	// level, for diagnosing the Tracer itself: each request and response
+	// with their headers, each redirect or refresh and whether it was
+	// followed, retries, and how each trace ended. Credentials are redacted
+	// unless RevealSecrets is set.
+	DebugLog *slog.Logger
+
+	// Insecure skips verification of TLS certificates.
+	Insecure bool
+
+	// MinTLSVersion, when set, is the lowest TLS version accepted, such as
+	// tls.VersionTLS12. A hop whose server cannot negotiate it fails with a
+	// *TLSVersionError. Versions below TLS 1.2 are otherwise refused by Go.
+	MinTLSVersion uint16
+
+	// CheckCertHostnames records each https hop's certificate and warns when
+	// the hop's host is not among its subject alternative names. Such a
+	// mismatch only gets past verification when Insecure is set.
+	CheckCertHostnames bool
+
+	// CertExpiryWarn warns about each https hop whose certificate expires
+	// within this long. Zero disables the check.
+	CertExpiryWarn time.Duration
+
+	// FailOnCertExpiry records the certificates within CertExpiryWarn, or
+	// DefaultCertExpiryWarn when it is zero, as failures rather than
+	// warnings.
+	FailOnCertExpiry bool
+
+	// HTTP3 tries each https request over HTTP/3 (QUIC) first, falling back
+	// to HTTP/2 or HTTP/1.1 for hosts which do not answer. It cannot be
+	// combined with a proxy or UnixSocket, and HTTP/3 connections are
+	// resolved by the system resolver rather than DNSServer.
+	HTTP3 bool
+
+	// AllowOneUpgrade permits a chain a single change of scheme, from http
+	// to https. Any other change, such as a downgrade back to http, aborts
+	// the trace with a *PolicyError.
+	AllowOneUpgrade bool
+
+	// EnableCookies gives each trace its own cookie jar, so cookies set by a
+	// hop are sent with the following ones as a browser would. Redirect
+	// loops are then told apart by whether the cookies change on each visit.
+	EnableCookies bool
+
+	// ProbeWebSocket attempts the upgrade handshake with a ws: or wss:
+	// endpoint a redirect leads to, recording the response as a final hop.
+	// Otherwise the endpoint is recorded without being contacted.
+	ProbeWebSocket bool
+
+	// Punycode records internationalized host names in hops in their
+	// punycode (xn--) form, as they are sent, rather than in Unicode. Requests
+	// always use punycode.
+	Punycode bool
+
+	// AllowHosts, when not empty, aborts the trace with a *PolicyError when
+	// the input URL or a redirect is to a host matching none of these
+	// patterns. DenyHosts does the same for a host matching any of its
+	// patterns, and takes precedence. Patterns are path.Match globs, such as
+	// *.example.com, compared without regard to case against the host
+	// without its port, in punycode for internationalized names.
+	AllowHosts []string
+	DenyHosts  []string
+
+	// FollowFunc, when set, is consulted for every redirect and refresh the
+	// other options allow, with prev the request which was redirected and
+	// next the one which would follow it. Returning false ends the chain at
+	// prev's response, which is kept as the final hop, with a StopReason. It
+	// is called on the goroutine running the trace, one redirect at a time.
+	FollowFunc func(prev, next *http.Request) bool
+
+	// SameHostOnly ends the chain, keeping the last response, when a redirect
+	// would leave the host of the input URL.
+	SameHostOnly bool
+
+	// Header holds headers sent on every request of every chain, including
+	// those made after a redirect. Headers set on a Request take precedence.
+	// As with http.Client, Authorization, WWW-Authenticate and Cookie are
+	// only sent to the input URL's host and its subdomains.
+	Header http.Header
+
+	// RequestIDHeader names a header carrying a request ID on every request so
+	// that hops can be correlated with server logs. Each hop gets a fresh
+	// random UUID unless RequestID is set.
+	RequestIDHeader string
+
+	// RequestID is a fixed value sent in RequestIDHeader in place of
+	// generated UUIDs.
+	RequestID string
+
+	// SlowHopThreshold labels any hop whose response took longer than it to
+	// arrive with LabelSlow. Zero disables the check.
+	SlowHopThreshold time.Duration
+
+	// FailOnSlow records a failure on the TraceResult for every slow hop.
+	FailOnSlow bool
+
+	// MaxHops records a failure on the TraceResult, listing its chain, when
+	// the chain has more hops than this. One asserts that a URL does not
+	// redirect at all. Zero disables the check.
+	MaxHops int
+
+	// RequireFinalScheme records a failure on the TraceResult when the final
+	// hop's URL does not use this scheme, such as https.
+	RequireFinalScheme string
+
+	// FailOnStatus records a failure on the TraceResult when the final hop's
+	// status is in the set.
+	FailOnStatus *StatusSet
+
+	// FailOnTransitions records a failure on the TraceResult for every hop
+	// reached by a redirect with one of these transition labels, such as
+	// LabelHostChange. See IsTransition.
+	FailOnTransitions []string
+
+	// WarnOnSchemeFlapping records a warning on the TraceResult when the
+	// chain changes scheme more than once, e.g. http to https and back.
+	// FailOnSchemeFlapping records a failure instead.
+	WarnOnSchemeFlapping bool
+	FailOnSchemeFlapping bool
+
+	// WarnOnExternalDomain records a warning on the TraceResult naming the
+	// first hop whose registered domain differs from the first hop's, as
+	// when a chain passes to another organization. Hosts without a
+	// registered domain, such as IP addresses, are compared whole.
+	// FailOnExternalDomain records a failure instead.
+	WarnOnExternalDomain bool
+	FailOnExternalDomain bool
+
+	// FailFast stops Run at the first result which has an error or failures.
+	// Results reported before that point are flushed and Run returns a
+	// *FailFastError.
+	FailFast bool
+
+	// Repeat traces each URL this many times in Run, recording every distinct
+	// chain observed on the reported result. Values below two trace once.
+	Repeat int
+
+	// MaxHeaderBytes limits the size of each response's headers. A hop which
+	// exceeds it ends the trace with an error naming the hop. When zero the
+	// transport's own limit applies.
+	MaxHeaderBytes int64
+
+	// MaxURLLength limits the length in bytes of every URL a redirect or
+	// refresh leads to. A longer URL aborts the trace with a *PolicyError
+	// naming the hop. When zero there is no limit; DefaultMaxURLLength is a
+	// reasonable choice for untrusted inputs.
+	MaxURLLength int
+
+	// MaxTotalBytes limits the response body bytes read across every hop of a
+	// chain, complementing the per-response MaxBodySize. A chain which exceeds
+	// it ends with a *PolicyError. When zero there is no limit.
+	MaxTotalBytes int64
+
+	// Timing records a DNS, connect, TLS and time to first byte breakdown on
+	// every hop.
+	Timing bool
+
+	// Retries is the number of times a trace is retried when it fails with a
+	// network error or ends with 429 Too Many Requests or a status in
+	// RetryOnStatus.
+	Retries int
+
+	// MaxRetriesPerHost, when positive, caps the retries made across the
+	// whole batch for input URLs on one host and port. Once a host has used
+	// them up its URLs are no longer retried, and those not yet traced are
+	// skipped with a *HostSkippedError.
+	MaxRetriesPerHost int
+
+	// RetryOnStatus lists further final statuses, such as 502 and 503, which
+	// are retried like 429. Others are treated as final.
+	RetryOnStatus *StatusSet
+
+	// RetryBaseDelay and RetryMaxDelay control the backoff between retries.
+	// Before retry n the Tracer sleeps for a random duration between zero and
+	// min(RetryMaxDelay, RetryBaseDelay * 2^(n-1)). They default to
+	// DefaultRetryBaseDelay and DefaultRetryMaxDelay.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// MaxBodySize limits how many bytes of a response body are read when an
+	// option needs to inspect it. It defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+
+	// BodyRegex is matched against the final response body. The first capture
+	// group of the first match, or the whole match when the expression has no
+	// groups, is stored in TraceResult.BodyMatch.
+	BodyRegex *regexp.Regexp
+
+	// CanonicalCheck parses the final response as HTML and records a warning
+	// when its <link rel="canonical"> differs from the final URL.
+	CanonicalCheck bool
+
+	// CanonicalFinal records the final URL in TraceResult.CanonicalFinal in
+	// a form comparable across runs and inputs: scheme and host lower cased,
+	// no default port and an empty path written as "/". The fragment is
+	// kept. CanonicalSortQuery also puts the query parameters in order by
+	// name.
+	CanonicalFinal     bool
+	CanonicalSortQuery bool
+
+	// CaptureHeaders records the response headers of every hop.
+	CaptureHeaders bool
+
+	// CaptureRequests records the method, URL and headers sent for every hop
+	// in Hop.Request. Credentials, in sensitive headers such as Authorization
+	// and Cookie or in URL user info, are redacted unless RevealSecrets is set.
+	CaptureRequests bool
+
+	// RevealSecrets keeps credentials in the requests recorded by
+	// CaptureRequests.
+	RevealSecrets bool
+
+	// RedactParams names query parameters, matched case-insensitively, whose
+	// values are replaced by REDACTED in the returned TraceResult, as are URL
+	// passwords, and in the spans given to TracerProvider. Requests are still
+	// made with the real values, and OnHop, Dump and DebugLog see them too.
+	// DefaultRedactParams lists common ones.
+	RedactParams []string
+
+	// RedactPathAfter names path segments, such as "reset", whose following
+	// segment is redacted like the values of RedactParams.
+	RedactPathAfter []string
+
+	// CompareHeaders diffs the response headers of the first and final hops,
+	// storing the result in TraceResult.HeaderDiff. It implies CaptureHeaders.
+	CompareHeaders bool
+
+	// OnHop, when set, is invoked with each hop as soon as its response
+	// arrives, before the TraceResult is assembled. Chain level analysis such
+	// as Hop.Labels is not yet available at that point. Run and RunRequests
+	// trace their inputs one at a time, so OnHop is called for one hop after
+	// another, in the order of each chain.
+	OnHop func(Hop)
+
+	// TracerProvider, when set, receives an OpenTelemetry span for every
+	// trace with a child span for each hop.
+	TracerProvider oteltrace.TracerProvider
+
+	// Cache, when set, replays fresh responses stored by earlier traces
+	// instead of requesting them again.
+	Cache *Cache
+
+	// Dump, when set, records every response received so that the chain can
+	// later be studied offline. Replay, when set, answers every request from
+	// the responses recorded in it and sends nothing over the network; a
+	// request with no recorded response fails the trace.
+	Dump   *Dump
+	Replay *Dump
+
+	// TraceID, when set, is recorded in every TraceResult so that output from
+	// several processes can be told apart.
+	TraceID string
+
+	// ChainHash records the ChainHash of every result in
+	// TraceResult.ChainHash, covering statuses as well as URLs when
+	// ChainHashStatuses is set.
+	ChainHash         bool
+	ChainHashStatuses bool
+
+	// Reporter receives each TraceResult produced by Run. When nil, results
+	// are discarded.
+	Reporter Reporter
+}
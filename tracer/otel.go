@@ -0,0 +1,60 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies the spans emitted by this package.
+const instrumentationName = "github.com/kkirsche/urltrace/tracer"
+
+// Span names emitted when Options.TracerProvider is set.
+const (
+	traceSpanName = "urltrace.trace"
+	hopSpanName   = "urltrace.hop"
+)
+
+// newSpanTracer returns the OpenTelemetry tracer spans are started from,
+// falling back to a no-op tracer when provider is nil.
+func newSpanTracer(provider oteltrace.TracerProvider) oteltrace.Tracer {
+	if provider == nil {
+		provider = noop.NewTracerProvider()
+	}
+	return provider.Tracer(instrumentationName)
+}
+
+// endTraceSpan annotates span with the outcome of result, redacted by
+// redact, and ends it.
+func endTraceSpan(span oteltrace.Span, result *TraceResult, redact *redactor) {
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("urltrace.hops", len(result.Hops)))
+	if final := result.Final(); final != nil {
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", final.StatusCode),
+			attribute.String("urltrace.final_url", redact.url(final.URL)),
+		)
+	}
+
+	if result.Err != nil {
+		err := redact.err(result.Err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"io"
+
+	"github.com/kkirsche/urltrace/tracer/tracerpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// ProtoReporter writes each result as a tracerpb.TraceResult protocol buffer
+// message, preceded by its length as a varint. This is the framing read by
+// protodelim.UnmarshalFrom and by Java's parseDelimitedFrom.
+type ProtoReporter struct {
+	w io.Writer
+
+	// Normalize passes each result through Normalize before it is written.
+	Normalize bool
+}
+
+// NewProtoReporter returns a ProtoReporter which writes to w.
+func NewProtoReporter(w io.Writer) *ProtoReporter {
+	return &ProtoReporter{w: w}
+}
+
+// Report writes result as a single length delimited message.
+func (r *ProtoReporter) Report(result *TraceResult) error {
+	if r.Normalize {
+		result = Normalize(result)
+	}
+	_, err := protodelim.MarshalTo(r.w, ToProto(result))
+	return err
+}
+
+// Flush is a no-op as ProtoReporter does not buffer.
+func (r *ProtoReporter) Flush() error {
+	return nil
+}
+
+// ToProto converts result to its protocol buffer form. Details without a
+// field in the schema, such as decoded data: URIs and repeated runs, are
+// left out.
+func ToProto(result *TraceResult) *tracerpb.TraceResult {
+	pb := &tracerpb.TraceResult{
+		InputUrl:         result.InputURL,
+		TraceId:          result.TraceID,
+		Proxy:            result.Proxy,
+		StopReason:       result.StopReason,
+		BodyMatch:        result.BodyMatch,
+		Canonical:        result.Canonical,
+		FinalUrl:         result.FinalURL,
+		CanonicalFinal:   result.CanonicalFinal,
+		ChainHash:        result.ChainHash,
+		TotalBytes:       result.TotalBytes,
+		TotalHeaderBytes: result.TotalHeaderBytes,
+		Attempts:         int32(result.Attempts),
+		RetryReasons:     result.RetryReasons,
+		Runs:             int32(result.Runs),
+		Warnings:         result.Warnings,
+		Failures:         result.Failures,
+	}
+	if result.Err != nil {
+		pb.Error = result.Err.Error()
+	}
+
+	for _, p := range result.TrackingParamsAdded {
+		pb.TrackingParamsAdded = append(pb.TrackingParamsAdded, &tracerpb.TrackingParam{
+			Name:  p.Name,
+			Hop:   int32(p.Hop),
+			Known: p.Known,
+		})
+	}
+
+	for i := range result.Hops {
+		pb.Hops = append(pb.Hops, hopToProto(&result.Hops[i]))
+	}
+	return pb
+}
+
+func hopToProto(hop *Hop) *tracerpb.Hop {
+	pb := &tracerpb.Hop{
+		Url:              hop.URL,
+		Host:             hop.Host,
+		Method:           hop.Method,
+		Status:           int32(hop.StatusCode),
+		RequestId:        hop.RequestID,
+		DurationNs:       int64(hop.Duration),
+		Protocol:         hop.Protocol,
+		HeaderBytes:      hop.HeaderBytes,
+		BodyBytes:        hop.BodyBytes,
+		Location:         hop.Location,
+		RedirectUrl:      hop.RedirectURL,
+		Cached:           hop.Cached,
+		Replayed:         hop.Replayed,
+		Labels:           hop.Labels,
+		ResolvedAddrs:    hop.ResolvedAddrs,
+		Server:           hop.Server,
+		Via:              hop.Via,
+		Etag:             hop.ETag,
+		LastModified:     hop.LastModified,
+		TlsVersion:       hop.TLSVersion,
+		Cnames:           hop.CNAMEs,
+		DnsRecords:       hop.DNSRecords,
+		RemoteAddr:       hop.RemoteAddr,
+		RegisteredDomain: hop.RegisteredDomain,
+		Tunnel:           hop.Tunnel,
+	}
+
+	if t := hop.Timing; t != nil {
+		pb.Timing = &tracerpb.Timing{
+			StartedUnixNano: t.Started.UnixNano(),
+			DnsNs:           int64(t.DNS),
+			ConnectNs:       int64(t.Connect),
+			TlsNs:           int64(t.TLS),
+			TtfbNs:          int64(t.TTFB),
+			TotalNs:         int64(t.Total),
+		}
+	}
+	if r := hop.Refresh; r != nil {
+		pb.Refresh = &tracerpb.Refresh{Source: r.Source, Delay: int32(r.Delay), Url: r.URL}
+	}
+	if l := hop.Link; l != nil {
+		pb.Link = &tracerpb.Link{Rel: l.Rel, Url: l.URL}
+	}
+	if c := hop.Certificate; c != nil {
+		pb.Certificate = &tracerpb.Certificate{
+			Sans:          c.SANs,
+			NotAfterUnix:  c.NotAfter.Unix(),
+			DaysRemaining: int32(c.DaysRemaining),
+			HostMismatch:  c.HostMismatch,
+		}
+	}
+	if len(hop.Header) > 0 {
+		pb.Headers = make(map[string]*tracerpb.HeaderValues, len(hop.Header))
+		for name, values := range hop.Header {
+			pb.Headers[name] = &tracerpb.HeaderValues{Values: values}
+		}
+	}
+	return pb
+}
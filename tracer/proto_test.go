@@ -0,0 +1,71 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/kkirsche/urltrace/tracer/tracerpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	result := &TraceResult{
+		InputURL: "http://example.com/",
+		Hops: []Hop{{
+			URL:              "http://example.com/",
+			Host:             "example.com",
+			Method:           "GET",
+			StatusCode:       200,
+			RegisteredDomain: "example.com",
+			CNAMEs:           []string{"example.com", "edge.cdn.test"},
+			DNSRecords:       []string{"192.0.2.1", "2001:db8::1"},
+			RemoteAddr:       "192.0.2.1:80",
+			Tunnel:           "bastion.internal:3128",
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := NewProtoReporter(&buf).Report(result); err != nil {
+		t.Fatal(err)
+	}
+	var pb tracerpb.TraceResult
+	if err := protodelim.UnmarshalFrom(bufio.NewReader(&buf), &pb); err != nil {
+		t.Fatal(err)
+	}
+
+	if pb.InputUrl != result.InputURL || len(pb.Hops) != 1 {
+		t.Fatalf("decoded %q with %d hops, want %q with 1", pb.InputUrl, len(pb.Hops), result.InputURL)
+	}
+	hop, want := pb.Hops[0], result.Hops[0]
+	if hop.Url != want.URL || hop.Host != want.Host || hop.Method != want.Method || hop.Status != int32(want.StatusCode) {
+		t.Errorf("hop = %s %s %s %d, want %s %s %s %d", hop.Method, hop.Url, hop.Host, hop.Status, want.Method, want.URL, want.Host, want.StatusCode)
+	}
+	if hop.RegisteredDomain != want.RegisteredDomain {
+		t.Errorf("registered_domain = %q, want %q", hop.RegisteredDomain, want.RegisteredDomain)
+	}
+	if !reflect.DeepEqual(hop.Cnames, want.CNAMEs) {
+		t.Errorf("cnames = %v, want %v", hop.Cnames, want.CNAMEs)
+	}
+	if hop.Tunnel != want.Tunnel {
+		t.Errorf("tunnel = %q, want %q", hop.Tunnel, want.Tunnel)
+	}
+	if !reflect.DeepEqual(hop.DnsRecords, want.DNSRecords) || hop.RemoteAddr != want.RemoteAddr {
+		t.Errorf("dns_records = %v, remote_addr = %q, want %v, %q", hop.DnsRecords, hop.RemoteAddr, want.DNSRecords, want.RemoteAddr)
+	}
+}
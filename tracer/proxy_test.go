@@ -0,0 +1,145 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// socks5Stub is a SOCKS5 proxy accepting username and password
+// authentication, which records the credentials each client sent and relays
+// its CONNECT to the requested address.
+type socks5Stub struct {
+	net.Listener
+	creds chan [2]string
+}
+
+func newSOCKS5Stub(t *testing.T) *socks5Stub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socks5Stub{Listener: ln, creds: make(chan [2]string, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socks5Stub) serve(conn net.Conn) {
+	defer conn.Close()
+	readN := func(n int) []byte {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil
+		}
+		return buf
+	}
+
+	// Greeting: version, method count and methods. Only method 2, username
+	// and password, is accepted.
+	greeting := readN(2)
+	if greeting == nil || readN(int(greeting[1])) == nil {
+		return
+	}
+	conn.Write([]byte{5, 2})
+
+	// RFC 1929 subnegotiation: version, username and password.
+	header := readN(2)
+	if header == nil {
+		return
+	}
+	user := readN(int(header[1]))
+	passwordLength := readN(1)
+	if user == nil || passwordLength == nil {
+		return
+	}
+	password := readN(int(passwordLength[0]))
+	s.creds <- [2]string{string(user), string(password)}
+	conn.Write([]byte{1, 0})
+
+	// CONNECT request: version, command, reserved, address type, address
+	// and port.
+	request := readN(4)
+	if request == nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		host = net.IP(readN(4)).String()
+	case 3:
+		host = string(readN(int(readN(1)[0])))
+	default:
+		return
+	}
+	port := binary.BigEndian.Uint16(readN(2))
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestSOCKS5ProxyCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	stub := newSOCKS5Stub(t)
+	defer stub.Close()
+
+	proxyURL := &url.URL{Scheme: "socks5", Host: stub.Addr().String(), User: url.UserPassword("tracer", "p@ss:word")}
+	tr, err := New(Options{Proxy: proxyURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tr.Trace(context.Background(), srv.URL)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if status := FinalStatus(result); status != http.StatusOK {
+		t.Fatalf("final status = %d, want 200", status)
+	}
+
+	select {
+	case creds := <-stub.creds:
+		if creds != [2]string{"tracer", "p@ss:word"} {
+			t.Errorf("proxy received user %q password %q, want tracer p@ss:word", creds[0], creds[1])
+		}
+	default:
+		t.Fatal("proxy received no credentials")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactParams are query parameters which commonly carry secrets, for
+// use as Options.RedactParams.
+var DefaultRedactParams = []string{
+	"access_token", "api_key", "apikey", "auth", "key", "password", "passwd",
+	"secret", "session", "sessionid", "sig", "signature", "token",
+}
+
+// absoluteURL matches the absolute URLs embedded in free text such as error
+// messages.
+var absoluteURL = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// redactor masks the values of sensitive query parameters, the path segments
+// following sensitive ones and URL passwords in a TraceResult.
+type redactor struct {
+	params    map[string]bool
+	pathAfter map[string]bool
+}
+
+// newRedactor returns a redactor for the options, or nil when nothing is to
+// be redacted.
+func newRedactor(opts Options) *redactor {
+	if len(opts.RedactParams) == 0 && len(opts.RedactPathAfter) == 0 {
+		return nil
+	}
+	r := &redactor{params: map[string]bool{}, pathAfter: map[string]bool{}}
+	for _, p := range opts.RedactParams {
+		r.params[strings.ToLower(p)] = true
+	}
+	for _, s := range opts.RedactPathAfter {
+		r.pathAfter[strings.ToLower(strings.Trim(s, "/"))] = true
+	}
+	return r
+}
+
+// url returns raw with its sensitive values replaced by REDACTED. A value
+// which cannot be parsed as a URL, or holds nothing to redact, is returned as
+// it is, so that a Location such as /\host/ is not re-encoded.
+func (r *redactor) url(raw string) string {
+	if r == nil {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	changed := false
+	if password, ok := u.User.Password(); ok && password != "" {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+		changed = true
+	}
+
+	if len(r.pathAfter) > 0 && u.Path != "" {
+		segments := strings.Split(u.Path, "/")
+		pathChanged := false
+		for i := 0; i+1 < len(segments); i++ {
+			if r.pathAfter[strings.ToLower(segments[i])] && segments[i+1] != "" {
+				segments[i+1] = redacted
+				pathChanged = true
+				i++
+			}
+		}
+		if pathChanged {
+			changed = true
+			u.Path = strings.Join(segments, "/")
+			u.RawPath = ""
+		}
+	}
+
+	if u.RawQuery != "" {
+		pairs := strings.Split(u.RawQuery, "&")
+		for i, pair := range pairs {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok || value == "" {
+				continue
+			}
+			if n, err := url.QueryUnescape(name); err == nil && r.params[strings.ToLower(n)] {
+				pairs[i] = name + "=" + redacted
+				changed = true
+			}
+		}
+		u.RawQuery = strings.Join(pairs, "&")
+	}
+	if !changed {
+		return raw
+	}
+	return u.String()
+}
+
+// text redacts every absolute URL within s.
+func (r *redactor) text(s string) string {
+	if r == nil {
+		return s
+	}
+	return absoluteURL.ReplaceAllStringFunc(s, r.url)
+}
+
+func (r *redactor) texts(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = r.text(v)
+	}
+	return out
+}
+
+// header returns a copy of h with the URLs in its values redacted.
+func (r *redactor) header(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		out[name] = r.headerValues(name, values)
+	}
+	return out
+}
+
+// headerValues redacts the values of the named header. Location and
+// Content-Location may hold relative URLs, which are redacted as URLs.
+func (r *redactor) headerValues(name string, values []string) []string {
+	switch name {
+	case "Location", "Content-Location":
+		redactedValues := make([]string, len(values))
+		for i, v := range values {
+			redactedValues[i] = r.url(v)
+		}
+		return redactedValues
+	default:
+		return r.texts(values)
+	}
+}
+
+// result redacts every URL of result in place.
+func (r *redactor) result(result *TraceResult) {
+	result.InputURL = r.url(result.InputURL)
+	result.FinalURL = r.url(result.FinalURL)
+	result.CanonicalFinal = r.url(result.CanonicalFinal)
+	result.Canonical = r.url(result.Canonical)
+	result.StopReason = r.text(result.StopReason)
+	result.RetryReasons = r.texts(result.RetryReasons)
+	result.Warnings = r.texts(result.Warnings)
+	result.Failures = r.texts(result.Failures)
+	for i := range result.DistinctChains {
+		result.DistinctChains[i].Hops = r.texts(result.DistinctChains[i].Hops)
+	}
+	result.Err = r.err(result.Err)
+	if result.HeaderDiff != nil {
+		diff := *result.HeaderDiff
+		diff.Added = r.header(diff.Added)
+		diff.Removed = r.header(diff.Removed)
+		if diff.Changed != nil {
+			diff.Changed = make(map[string]HeaderChange, len(result.HeaderDiff.Changed))
+			for name, change := range result.HeaderDiff.Changed {
+				diff.Changed[name] = HeaderChange{From: r.headerValues(name, change.From), To: r.headerValues(name, change.To)}
+			}
+		}
+		result.HeaderDiff = &diff
+	}
+
+	for i := range result.Hops {
+		hop := &result.Hops[i]
+		hop.URL = r.url(hop.URL)
+		hop.Location = r.url(hop.Location)
+		hop.RedirectURL = r.url(hop.RedirectURL)
+		if hop.Refresh != nil {
+			refresh := *hop.Refresh
+			refresh.URL = r.url(refresh.URL)
+			hop.Refresh = &refresh
+		}
+		if hop.Link != nil {
+			link := *hop.Link
+			link.URL = r.url(link.URL)
+			hop.Link = &link
+		}
+		hop.Header = r.header(hop.Header)
+		if hop.Informational != nil {
+			informational := make([]Informational, len(hop.Informational))
+			for j, info := range hop.Informational {
+				informational[j] = Informational{StatusCode: info.StatusCode, Header: r.header(info.Header)}
+			}
+			hop.Informational = informational
+		}
+		if hop.Request != nil {
+			sent := *hop.Request
+			sent.URL = r.url(sent.URL)
+			sent.Header = r.header(sent.Header)
+			hop.Request = &sent
+		}
+	}
+}
+
+// err returns err with the URLs in its message redacted, or err itself when
+// there is nothing to redact.
+func (r *redactor) err(err error) error {
+	if err == nil {
+		return nil
+	}
+	if msg := r.text(err.Error()); msg != err.Error() {
+		return &redactedError{msg: msg, err: err}
+	}
+	return err
+}
+
+// redactedError is an error whose message has been redacted. It unwraps to
+// the original error.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
@@ -0,0 +1,165 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RefreshHeader marks a refresh sent as a Refresh response header.
+	RefreshHeader = "header"
+
+	// RefreshMeta marks a refresh sent as an HTML
+	// <meta http-equiv="refresh"> tag.
+	RefreshMeta = "meta"
+)
+
+// Refresh describes a refresh which led from a hop to the next one when
+// Options.FollowMetaRefresh is set.
+type Refresh struct {
+	// Source is RefreshHeader or RefreshMeta.
+	Source string `json:"source"`
+
+	// Delay is the number of seconds the page asked to wait before
+	// refreshing. The tracer does not wait.
+	Delay int `json:"delay"`
+
+	// URL is the refresh target resolved against the hop's URL.
+	URL string `json:"url"`
+}
+
+// parseRefresh parses a refresh value of the form "N" or "N;url=...". The url
+// may be quoted, and its "url=" prefix is optional. ok is false when value is
+// not a valid refresh.
+func parseRefresh(value string) (delay int, target string, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, "", false
+	}
+
+	// The delay is a non-negative number, of which any fraction is ignored.
+	end := strings.IndexFunc(value, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if end == -1 {
+		end = len(value)
+	}
+	digits := value[:end]
+	if i := strings.IndexByte(digits, '.'); i != -1 {
+		digits = digits[:i]
+	}
+	if digits == "" {
+		return 0, "", false
+	}
+	delay, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, "", false
+	}
+
+	rest := strings.TrimLeft(value[end:], " \t")
+	if rest == "" {
+		return delay, "", true
+	}
+	if rest[0] != ';' && rest[0] != ',' {
+		return 0, "", false
+	}
+	rest = strings.TrimSpace(rest[1:])
+
+	if len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+		if after := strings.TrimLeft(rest[3:], " \t"); strings.HasPrefix(after, "=") {
+			rest = strings.TrimSpace(after[1:])
+		}
+	}
+	if len(rest) > 0 && (rest[0] == '\'' || rest[0] == '"') {
+		if i := strings.IndexByte(rest[1:], rest[0]); i != -1 {
+			rest = rest[1 : i+1]
+		} else {
+			rest = rest[1:]
+		}
+	}
+	return delay, strings.TrimSpace(rest), true
+}
+
+// isHTML reports whether resp declares an HTML body.
+func isHTML(resp *http.Response) bool {
+	return strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "html")
+}
+
+// nextRefresh returns the request following the Refresh header or meta
+// refresh of resp, whose body has already been read into body, recording the
+// refresh on the last hop of rec. It returns nil when there is nothing to
+// follow or the redirect policy stops the chain here.
+func (t *Tracer) nextRefresh(ctx context.Context, resp *http.Response, body []byte, rec *recorder) (*http.Request, error) {
+	if !t.opts.FollowMetaRefresh || isRedirect(resp.StatusCode) {
+		return nil, nil
+	}
+
+	source := RefreshHeader
+	delay, target, ok := parseRefresh(resp.Header.Get("Refresh"))
+	if !ok && body != nil && isHTML(resp) {
+		source = RefreshMeta
+		delay, target, ok = parseRefresh(findMetaRefresh(body))
+	}
+	if !ok || target == "" {
+		return nil, nil
+	}
+
+	next, err := resp.Request.URL.Parse(target)
+	if err != nil {
+		return nil, nil
+	}
+
+	if len(rec.hops) > 0 {
+		hop := &rec.hops[len(rec.hops)-1]
+		display := next.String()
+		if !t.opts.Punycode {
+			display = unicodeURL(display)
+		}
+		hop.Refresh = &Refresh{Source: source, Delay: delay, URL: display}
+		hop.RedirectURL = display
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, next.String(), nil)
+	if err != nil {
+		return nil, nil
+	}
+	req.Header = followHeader(resp.Request, rec.initial, req.URL)
+	req.Response = resp
+
+	if err := t.checkRedirect(req, viaOf(resp)); err != nil {
+		if errors.Is(err, http.ErrUseLastResponse) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return req, nil
+}
+
+// viaOf returns the requests which led to resp, oldest first, as
+// http.Client passes them to CheckRedirect.
+func viaOf(resp *http.Response) []*http.Request {
+	var via []*http.Request
+	for r := resp.Request; r != nil; {
+		via = append([]*http.Request{r}, via...)
+		if r.Response == nil {
+			break
+		}
+		r = r.Response.Request
+	}
+	return via
+}
@@ -0,0 +1,83 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chain is one distinct redirect chain observed while repeating a trace.
+type Chain struct {
+	// Hops holds "<status> <url>" for each hop of the chain.
+	Hops []string `json:"hops"`
+
+	// Count is the number of runs which produced this chain.
+	Count int `json:"count"`
+}
+
+// traceRepeated traces r n times. The last run's result is returned with
+// DistinctChains describing every chain seen, in order of first appearance,
+// and the duration of each of its hops in every run.
+func (t *Tracer) traceRepeated(ctx context.Context, r Request, n int) *TraceResult {
+	var (
+		result *TraceResult
+		runs   = make([]*TraceResult, 0, n)
+		chains []Chain
+		index  = map[string]int{}
+	)
+
+	for run := 0; run < n; run++ {
+		result = t.traceWithRetries(ctx, r)
+		runs = append(runs, result)
+
+		chain := chainOf(result)
+		key := strings.Join(chain, "\n")
+		if i, ok := index[key]; ok {
+			chains[i].Count++
+			continue
+		}
+		index[key] = len(chains)
+		chains = append(chains, Chain{Hops: chain, Count: 1})
+	}
+
+	result.Runs = n
+	result.DistinctChains = chains
+	for i := range result.Hops {
+		hop := &result.Hops[i]
+		hop.ProbeDurations = make([]time.Duration, n)
+		for run, r := range runs {
+			if i < len(r.Hops) && r.Hops[i].URL == hop.URL {
+				hop.ProbeDurations[run] = r.Hops[i].Duration
+			}
+		}
+	}
+	return result
+}
+
+// chainOf summarizes the hops of result, noting the error if the trace failed
+// so that failing and succeeding runs are told apart.
+func chainOf(result *TraceResult) []string {
+	chain := make([]string, 0, len(result.Hops)+1)
+	for _, hop := range result.Hops {
+		chain = append(chain, strconv.Itoa(hop.StatusCode)+" "+hop.URL)
+	}
+	if result.Err != nil {
+		chain = append(chain, "error: "+result.Err.Error())
+	}
+	return chain
+}
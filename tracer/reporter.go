@@ -0,0 +1,807 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Reporter renders trace results. Report is called once per traced URL and
+// Flush once after the final result.
+type Reporter interface {
+	Report(result *TraceResult) error
+	Flush() error
+}
+
+// TextReporter writes a human readable line per hop.
+type TextReporter struct {
+	w io.Writer
+
+	// FullURL displays each hop's entire URL rather than only its host.
+	FullURL bool
+
+	// ShowPath adds the path to the host displayed when FullURL is unset.
+	// ShowQuery adds the query as well, and implies ShowPath.
+	ShowPath  bool
+	ShowQuery bool
+
+	// Verbose writes indented detail lines beneath each hop.
+	Verbose bool
+
+	// Protocol adds the HTTP version of each response, e.g. HTTP/3.
+	Protocol bool
+
+	// Curl writes, beneath each hop, a curl command repeating its request.
+	// It needs the requests recorded by Options.CaptureRequests.
+	Curl bool
+
+	// OnlyFinal writes a single "<status> <url>" line for the final hop in
+	// place of the full chain.
+	OnlyFinal bool
+
+	// GroupByDomain writes, once every result has been reported, each
+	// registered domain reached with the input URLs whose chains reached it.
+	GroupByDomain bool
+	domains       domainGroups
+}
+
+// NewTextReporter returns a TextReporter which writes to w.
+func NewTextReporter(w io.Writer, fullURL bool) *TextReporter {
+	return &TextReporter{w: w, FullURL: fullURL}
+}
+
+// Report writes a line for each hop followed by any error which ended the
+// trace.
+func (r *TextReporter) Report(result *TraceResult) error {
+	if r.GroupByDomain {
+		r.domains.add(result)
+	}
+
+	if r.OnlyFinal {
+		return r.reportFinal(result)
+	}
+
+	for i, hop := range result.Hops {
+		var line string
+		if r.FullURL {
+			line = fmt.Sprintf("Status: %d, Full URL: %s", hop.StatusCode, hop.URL)
+		} else {
+			line = fmt.Sprintf("Status: %d, Base URL: %s", hop.StatusCode, r.hostOf(&hop))
+		}
+
+		if hop.DataURI != nil {
+			line = fmt.Sprintf("Data URI: %s, %d bytes", hop.DataURI.MediaType, hop.DataURI.Size)
+			if hop.DataURI.Truncated {
+				line += " (truncated at the maximum body size)"
+			}
+		}
+
+		if ws := hop.WebSocket; ws != nil {
+			switch {
+			case !ws.Probed:
+				line = "WebSocket endpoint: " + hop.URL + ", not probed"
+			case ws.Upgraded:
+				line += ", WebSocket: upgraded"
+			default:
+				line += ", WebSocket: upgrade refused"
+			}
+		}
+
+		if r.Protocol && hop.Protocol != "" {
+			line += ", Protocol: " + hop.Protocol
+		}
+
+		if hop.RequestID != "" {
+			line += ", Request ID: " + hop.RequestID
+		}
+
+		if len(hop.ResolvedAddrs) > 0 {
+			line += ", Resolved Addrs: " + strings.Join(hop.ResolvedAddrs, " ")
+		}
+
+		if hop.Location != "" {
+			line += ", Location: " + hop.Location
+			if hop.RedirectURL != "" && hop.RedirectURL != hop.Location {
+				line += " (resolved: " + hop.RedirectURL + ")"
+			}
+		}
+
+		if hop.Refresh != nil {
+			line += fmt.Sprintf(", Refresh (%s, %ds): %s", hop.Refresh.Source, hop.Refresh.Delay, hop.Refresh.URL)
+		}
+
+		if hop.Link != nil {
+			line += fmt.Sprintf(", Link (rel=%s): %s", hop.Link.Rel, hop.Link.URL)
+		}
+
+		labels := hop.Labels
+		if hop.Cached {
+			labels = append([]string{"cached"}, labels...)
+		}
+		if hop.Replayed {
+			labels = append([]string{"replayed"}, labels...)
+		}
+		if len(labels) > 0 {
+			line += " [" + strings.Join(labels, ", ") + "]"
+		}
+
+		if _, err := fmt.Fprintln(r.w, line); err != nil {
+			return err
+		}
+
+		if err := r.reportInformational(hop.Informational); err != nil {
+			return err
+		}
+
+		if r.Verbose {
+			if err := r.reportDetails(result, i); err != nil {
+				return err
+			}
+		}
+
+		if len(hop.DNSRecords) > 0 {
+			records := make([]string, len(hop.DNSRecords))
+			for j, addr := range hop.DNSRecords {
+				records[j] = addr
+				if addr == hop.RemoteAddr {
+					records[j] += " (connected)"
+				}
+			}
+			if _, err := fmt.Fprintf(r.w, "    DNS Records: %s\n", strings.Join(records, ", ")); err != nil {
+				return err
+			}
+		}
+
+		if len(hop.CNAMEs) > 0 {
+			host := (&url.URL{Host: hop.Host}).Hostname()
+			chain := append([]string{host}, hop.CNAMEs...)
+			if _, err := fmt.Fprintf(r.w, "    CNAME Chain: %s\n", strings.Join(chain, " -> ")); err != nil {
+				return err
+			}
+		}
+
+		if r.Curl && hop.Request != nil {
+			if _, err := fmt.Fprintf(r.w, "    %s\n", CurlCommand(hop.Request)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if timing := TotalTiming(result); timing != nil {
+		if err := r.reportTiming(result, timing); err != nil {
+			return err
+		}
+	}
+
+	if result.ChainHash != "" {
+		if _, err := fmt.Fprintf(r.w, "Chain Hash: %s\n", result.ChainHash); err != nil {
+			return err
+		}
+	}
+
+	if result.BodyMatch != "" {
+		if _, err := fmt.Fprintf(r.w, "Body Match: %s\n", result.BodyMatch); err != nil {
+			return err
+		}
+	}
+
+	if len(result.TrackingParamsAdded) > 0 {
+		params := make([]string, len(result.TrackingParamsAdded))
+		for i, p := range result.TrackingParamsAdded {
+			params[i] = fmt.Sprintf("%s (hop %d)", p.Name, p.Hop)
+			if p.Known {
+				params[i] = fmt.Sprintf("%s (hop %d, known tracker)", p.Name, p.Hop)
+			}
+		}
+		if _, err := fmt.Fprintf(r.w, "Query Parameters Added: %s\n", strings.Join(params, ", ")); err != nil {
+			return err
+		}
+	}
+
+	if result.CanonicalFinal != "" {
+		if _, err := fmt.Fprintf(r.w, "Canonical Final URL: %s\n", result.CanonicalFinal); err != nil {
+			return err
+		}
+	}
+
+	if result.HeaderDiff != nil {
+		if err := r.reportHeaderDiff(result.HeaderDiff); err != nil {
+			return err
+		}
+	}
+
+	if result.StopReason != "" {
+		if _, err := fmt.Fprintf(r.w, "Stopped: %s\n", result.StopReason); err != nil {
+			return err
+		}
+	}
+
+	if result.Proxy != "" {
+		if _, err := fmt.Fprintf(r.w, "Proxy: %s\n", result.Proxy); err != nil {
+			return err
+		}
+	}
+
+	if r.Verbose && result.TotalBytes+result.TotalHeaderBytes > 0 {
+		if _, err := fmt.Fprintf(r.w, "Total Bytes: %d (headers %d, bodies %d)\n", result.TotalBytes+result.TotalHeaderBytes, result.TotalHeaderBytes, result.TotalBytes); err != nil {
+			return err
+		}
+	}
+
+	if result.Attempts > 1 {
+		if _, err := fmt.Fprintf(r.w, "Attempts: %d\n", result.Attempts); err != nil {
+			return err
+		}
+		for _, reason := range result.RetryReasons {
+			if _, err := fmt.Fprintf(r.w, "    Retried: %s\n", reason); err != nil {
+				return err
+			}
+		}
+	}
+
+	if result.Runs > 0 {
+		if err := r.reportChains(result); err != nil {
+			return err
+		}
+	}
+
+	if err := r.reportFailures(result); err != nil {
+		return err
+	}
+
+	if result.Err != nil {
+		_, err := fmt.Fprintf(r.w, "Error: %s: %v\n", result.InputURL, result.Err)
+		return err
+	}
+	return nil
+}
+
+// hostOf returns the part of hop's URL displayed when FullURL is unset: its
+// host, followed by the path and query when ShowPath or ShowQuery are set.
+func (r *TextReporter) hostOf(hop *Hop) string {
+	if !r.ShowPath && !r.ShowQuery {
+		return hop.Host
+	}
+
+	u, err := url.Parse(hop.URL)
+	if err != nil {
+		return hop.Host
+	}
+	display := hop.Host + u.EscapedPath()
+	if r.ShowQuery && u.RawQuery != "" {
+		display += "?" + u.RawQuery
+	}
+	return display
+}
+
+// reportFinal writes the final hop's status and URL followed by any error
+// which ended the trace.
+func (r *TextReporter) reportFinal(result *TraceResult) error {
+	if final := result.Final(); final != nil {
+		u := r.hostOf(final)
+		if r.FullURL {
+			u = final.URL
+		}
+		if _, err := fmt.Fprintf(r.w, "%d %s\n", final.StatusCode, u); err != nil {
+			return err
+		}
+	}
+
+	if err := r.reportFailures(result); err != nil {
+		return err
+	}
+
+	if result.Err != nil {
+		_, err := fmt.Fprintf(r.w, "Error: %s: %v\n", result.InputURL, result.Err)
+		return err
+	}
+	return nil
+}
+
+// reportDetails writes the verbose detail lines of the i-th hop of result.
+func (r *TextReporter) reportDetails(result *TraceResult, i int) error {
+	hop := result.Hops[i]
+
+	// The request URL is shown as serialized for the wire so that any
+	// normalization of the input, such as query escaping, is visible.
+	details := []string{"Request URL: " + hop.URL}
+	if i == 0 && hop.URL != result.InputURL {
+		details = append(details, "Input URL: "+result.InputURL)
+	}
+	if hop.RegisteredDomain != "" {
+		details = append(details, "Registered Domain: "+hop.RegisteredDomain)
+	}
+	if hop.Certificate != nil {
+		details = append(details, "Certificate SANs: "+strings.Join(hop.Certificate.SANs, ", "))
+		details = append(details, fmt.Sprintf("Certificate Expires: %s (%d days remaining)", hop.Certificate.NotAfter.UTC().Format(time.DateOnly), hop.Certificate.DaysRemaining))
+	}
+	if hop.TLSVersion != "" {
+		details = append(details, "TLS Version: "+hop.TLSVersion)
+	}
+	if hop.Tunnel != "" {
+		details = append(details, fmt.Sprintf("Tunnel: CONNECT %s established through %s", hop.Host, hop.Tunnel))
+	}
+	if hop.Server != "" {
+		details = append(details, "Server: "+hop.Server)
+	}
+	if len(hop.Via) > 0 {
+		details = append(details, "Via: "+strings.Join(hop.Via, ", "))
+	}
+	if hop.ETag != "" {
+		details = append(details, "ETag: "+hop.ETag)
+	}
+	if hop.LastModified != "" {
+		details = append(details, "Last-Modified: "+hop.LastModified)
+	}
+	if hop.HeaderBytes > 0 {
+		details = append(details, fmt.Sprintf("Bytes: %d (headers %d, body %d)", hop.HeaderBytes+hop.BodyBytes, hop.HeaderBytes, hop.BodyBytes))
+	}
+
+	for _, detail := range details {
+		if _, err := fmt.Fprintf(r.w, "    %s\n", detail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportInformational writes a line beneath a hop for each 1xx response it
+// received, with the headers that came with it.
+func (r *TextReporter) reportInformational(responses []Informational) error {
+	for _, info := range responses {
+		line := fmt.Sprintf("    %d %s", info.StatusCode, http.StatusText(info.StatusCode))
+		names := make([]string, 0, len(info.Header))
+		for name := range info.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			line += fmt.Sprintf(", %s: %s", name, strings.Join(info.Header[name], ", "))
+		}
+		if _, err := fmt.Fprintln(r.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportTiming writes an aligned table of each hop's timing followed by the
+// totals for the chain.
+func (r *TextReporter) reportTiming(result *TraceResult, total *Timing) error {
+	tw := tabwriter.NewWriter(r.w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Hop\tDNS\tConnect\tTLS\tTTFB\tTotal\t")
+	row := func(name string, t *Timing) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", name,
+			t.DNS.Round(time.Microsecond), t.Connect.Round(time.Microsecond), t.TLS.Round(time.Microsecond),
+			t.TTFB.Round(time.Microsecond), t.Total.Round(time.Microsecond))
+	}
+	for i, hop := range result.Hops {
+		if hop.Timing != nil {
+			row(strconv.Itoa(i), hop.Timing)
+		}
+	}
+	row("all", total)
+	return tw.Flush()
+}
+
+// reportChains writes whether a repeated trace was consistent, listing each
+// distinct chain when it was not.
+func (r *TextReporter) reportChains(result *TraceResult) error {
+	if result.Consistent() {
+		_, err := fmt.Fprintf(r.w, "Consistent: same chain across %d runs\n", result.Runs)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(r.w, "Inconsistent: %d distinct chains across %d runs\n", len(result.DistinctChains), result.Runs); err != nil {
+		return err
+	}
+	for _, chain := range result.DistinctChains {
+		if _, err := fmt.Fprintf(r.w, "    %dx: %s\n", chain.Count, strings.Join(chain.Hops, " -> ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportFailures writes a line for each warning and failed policy check.
+func (r *TextReporter) reportFailures(result *TraceResult) error {
+	for _, warning := range result.Warnings {
+		if _, err := fmt.Fprintf(r.w, "Warning: %s: %s\n", result.InputURL, warning); err != nil {
+			return err
+		}
+	}
+	for _, failure := range result.Failures {
+		if _, err := fmt.Fprintf(r.w, "Failed: %s: %s\n", result.InputURL, failure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportHeaderDiff writes one line per differing header, sorted by name.
+func (r *TextReporter) reportHeaderDiff(diff *HeaderDiff) error {
+	if diff.Empty() {
+		_, err := fmt.Fprintln(r.w, "Headers: no changes between first and final hop")
+		return err
+	}
+
+	var lines []string
+	for name, values := range diff.Added {
+		lines = append(lines, fmt.Sprintf("  + %s: %s", name, strings.Join(values, ", ")))
+	}
+	for name, values := range diff.Removed {
+		lines = append(lines, fmt.Sprintf("  - %s: %s", name, strings.Join(values, ", ")))
+	}
+	for name, change := range diff.Changed {
+		lines = append(lines, fmt.Sprintf("  ~ %s: %s -> %s", name, strings.Join(change.From, ", "), strings.Join(change.To, ", ")))
+	}
+	// Sort on the header name, skipping the marker, so related lines group.
+	sort.Slice(lines, func(i, j int) bool { return lines[i][4:] < lines[j][4:] })
+
+	if _, err := fmt.Fprintln(r.w, "Headers changed between first and final hop:"); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(r.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes the per-domain summary buffered for GroupByDomain, and is a
+// no-op otherwise.
+func (r *TextReporter) Flush() error {
+	if !r.GroupByDomain || len(r.domains.groups) == 0 {
+		return nil
+	}
+	return r.domains.write(r.w)
+}
+
+// JSONReporter writes one JSON object per traced URL (newline delimited).
+// Object keys, including header names, are always written in a fixed order.
+type JSONReporter struct {
+	enc *json.Encoder
+
+	// Normalize makes output for the same chain byte-identical across runs by
+	// passing each result through Normalize first.
+	Normalize bool
+
+	// Flatten writes each result as a single level object whose keys are
+	// dotted paths, e.g. hops.0.status, for systems which cannot ingest
+	// nested JSON.
+	Flatten bool
+
+	// KeyPrefix is added to the start of every top-level key, and so to
+	// every key of flattened output, to avoid collisions when the output is
+	// merged with other data.
+	KeyPrefix string
+
+	// OmitEmpty leaves out every field whose value is zero, false, empty or
+	// null, including those such as a hop's url which are otherwise always
+	// written, to shrink large batches. A missing field reads as its zero
+	// value. Zero hop indexes, refresh delays, days remaining and data sizes
+	// are kept.
+	OmitEmpty bool
+}
+
+// NewJSONReporter returns a JSONReporter which writes to w.
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(w)}
+}
+
+// Report writes result as a single line of JSON.
+func (r *JSONReporter) Report(result *TraceResult) error {
+	if r.Normalize {
+		result = Normalize(result)
+	}
+	var v interface{} = result
+	if r.Flatten {
+		flat, err := flatten(result)
+		if err != nil {
+			return err
+		}
+		v = flat
+	}
+	if r.OmitEmpty {
+		pruned, err := omitEmpty(v)
+		if err != nil {
+			return err
+		}
+		v = pruned
+	}
+	return encodePrefixed(r.enc, v, r.KeyPrefix)
+}
+
+// encodePrefixed writes v with enc, adding prefix to the start of each of the
+// keys of the object v encodes to.
+func encodePrefixed(enc *json.Encoder, v interface{}, prefix string) error {
+	if prefix == "" {
+		return enc.Encode(v)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	prefixed := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		prefixed[prefix+key] = value
+	}
+	return enc.Encode(prefixed)
+}
+
+// Flush is a no-op as JSONReporter does not buffer.
+func (r *JSONReporter) Flush() error {
+	return nil
+}
+
+// HopsNDJSONReporter writes one JSON object per hop (newline delimited),
+// each carrying the input URL it belongs to.
+type HopsNDJSONReporter struct {
+	enc *json.Encoder
+
+	// Normalize passes each result through Normalize before it is written.
+	Normalize bool
+
+	// KeyPrefix is added to the start of every top-level key.
+	KeyPrefix string
+
+	// OmitEmpty leaves out empty fields as JSONReporter.OmitEmpty does.
+	OmitEmpty bool
+}
+
+// NewHopsNDJSONReporter returns a HopsNDJSONReporter which writes to w.
+func NewHopsNDJSONReporter(w io.Writer) *HopsNDJSONReporter {
+	return &HopsNDJSONReporter{enc: json.NewEncoder(w)}
+}
+
+type ndjsonHop struct {
+	InputURL string `json:"input_url"`
+	HopIndex int    `json:"hop_index"`
+	*Hop
+	StopReason string   `json:"stop_reason,omitempty"`
+	Failures   []string `json:"failures,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Report writes a line for each hop. The final line carries how the trace
+// ended, and a result with no hops is written as a single line so that
+// failures are not lost.
+func (r *HopsNDJSONReporter) Report(result *TraceResult) error {
+	if r.Normalize {
+		result = Normalize(result)
+	}
+
+	var errString string
+	if result.Err != nil {
+		errString = result.Err.Error()
+	}
+
+	if len(result.Hops) == 0 {
+		return r.encode(&ndjsonHop{
+			InputURL:   result.InputURL,
+			StopReason: result.StopReason,
+			Failures:   result.Failures,
+			Error:      errString,
+		})
+	}
+
+	for i := range result.Hops {
+		line := &ndjsonHop{
+			InputURL: result.InputURL,
+			HopIndex: i,
+			Hop:      &result.Hops[i],
+		}
+		if i == len(result.Hops)-1 {
+			line.StopReason = result.StopReason
+			line.Failures = result.Failures
+			line.Error = errString
+		}
+		if err := r.encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode writes a single line.
+func (r *HopsNDJSONReporter) encode(line *ndjsonHop) error {
+	var v interface{} = line
+	if r.OmitEmpty {
+		pruned, err := omitEmpty(line)
+		if err != nil {
+			return err
+		}
+		v = pruned
+	}
+	return encodePrefixed(r.enc, v, r.KeyPrefix)
+}
+
+// Flush is a no-op as HopsNDJSONReporter does not buffer.
+func (r *HopsNDJSONReporter) Flush() error {
+	return nil
+}
+
+// CSVReporter writes one CSV row per hop, preceded by a header row.
+type CSVReporter struct {
+	w           *csv.Writer
+	wroteHeader bool
+
+	// HeaderPrefix is added to the start of every column name.
+	HeaderPrefix string
+}
+
+// NewCSVReporter returns a CSVReporter which writes to w.
+func NewCSVReporter(w io.Writer) *CSVReporter {
+	return &CSVReporter{w: csv.NewWriter(w)}
+}
+
+var csvHeader = []string{"input_url", "hop", "status", "url", "location", "redirect_url", "labels", "stop_reason", "failures", "error"}
+
+// Report writes a row for each hop. A result with no hops is written as a
+// single row so that failures are not lost.
+func (r *CSVReporter) Report(result *TraceResult) error {
+	if !r.wroteHeader {
+		header := make([]string, len(csvHeader))
+		for i, name := range csvHeader {
+			header[i] = r.HeaderPrefix + name
+		}
+		if err := r.w.Write(header); err != nil {
+			return err
+		}
+		r.wroteHeader = true
+	}
+
+	var errString string
+	if result.Err != nil {
+		errString = result.Err.Error()
+	}
+	failures := strings.Join(result.Failures, ";")
+
+	if len(result.Hops) == 0 {
+		return r.w.Write([]string{result.InputURL, "", "", "", "", "", "", result.StopReason, failures, errString})
+	}
+
+	for i, hop := range result.Hops {
+		// Only the final row carries how the trace ended so each is reported once.
+		var rowStop, rowFailures, rowErr string
+		if i == len(result.Hops)-1 {
+			rowStop, rowFailures, rowErr = result.StopReason, failures, errString
+		}
+		record := []string{result.InputURL, strconv.Itoa(i), strconv.Itoa(hop.StatusCode), hop.URL, hop.Location, hop.RedirectURL, strings.Join(hop.Labels, ";"), rowStop, rowFailures, rowErr}
+		if err := r.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (r *CSVReporter) Flush() error {
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// StatusReporter writes only the final status code of each trace, one per
+// line, for shell scripts. A trace which ended without a response is written
+// as 000.
+type StatusReporter struct {
+	w io.Writer
+}
+
+// NewStatusReporter returns a StatusReporter which writes to w.
+func NewStatusReporter(w io.Writer) *StatusReporter {
+	return &StatusReporter{w: w}
+}
+
+// Report writes the status code of the final hop.
+func (r *StatusReporter) Report(result *TraceResult) error {
+	_, err := fmt.Fprintf(r.w, "%03d\n", FinalStatus(result))
+	return err
+}
+
+// Flush is a no-op as StatusReporter does not buffer.
+func (r *StatusReporter) Flush() error {
+	return nil
+}
+
+// TracerouteReporter writes each chain in the style of traceroute: a line
+// per numbered hop with the time each probe took, followed by their minimum,
+// average and maximum when the trace was repeated with Options.Repeat. A
+// probe which did not reach the hop is shown as *.
+type TracerouteReporter struct {
+	w io.Writer
+}
+
+// NewTracerouteReporter returns a TracerouteReporter which writes to w.
+func NewTracerouteReporter(w io.Writer) *TracerouteReporter {
+	return &TracerouteReporter{w: w}
+}
+
+// Report writes the numbered hops of result.
+func (r *TracerouteReporter) Report(result *TraceResult) error {
+	probes, noun := max(result.Runs, 1), "probes"
+	if probes == 1 {
+		noun = "probe"
+	}
+	if _, err := fmt.Fprintf(r.w, "urltrace to %s, %d %s\n", result.InputURL, probes, noun); err != nil {
+		return err
+	}
+
+	for i, hop := range result.Hops {
+		durations := hop.ProbeDurations
+		if len(durations) == 0 {
+			durations = []time.Duration{hop.Duration}
+		}
+
+		line := fmt.Sprintf("%2d  %3d  %s", i+1, hop.StatusCode, hop.URL)
+		var reached []time.Duration
+		for _, d := range durations {
+			if d == 0 {
+				line += "  *"
+				continue
+			}
+			reached = append(reached, d)
+			line += fmt.Sprintf("  %.3f ms", millis(d))
+		}
+		if len(durations) > 1 && len(reached) > 0 {
+			least, most, total := reached[0], reached[0], time.Duration(0)
+			for _, d := range reached {
+				least, most, total = min(least, d), max(most, d), total+d
+			}
+			line += fmt.Sprintf("  (min/avg/max %.3f/%.3f/%.3f ms)", millis(least), millis(total/time.Duration(len(reached))), millis(most))
+		}
+		if _, err := fmt.Fprintln(r.w, line); err != nil {
+			return err
+		}
+	}
+
+	if result.StopReason != "" {
+		if _, err := fmt.Fprintf(r.w, "Stopped: %s\n", result.StopReason); err != nil {
+			return err
+		}
+	}
+	if result.Err != nil {
+		_, err := fmt.Fprintf(r.w, "Error: %s: %v\n", result.InputURL, result.Err)
+		return err
+	}
+	return nil
+}
+
+// millis returns d in milliseconds, which traceroute shows to three decimal
+// places.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Flush is a no-op as TracerouteReporter does not buffer.
+func (r *TracerouteReporter) Flush() error {
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import "net/http"
+
+// Request describes a URL to trace along with settings which apply only to
+// that trace.
+type Request struct {
+	// URL is the URL to trace.
+	URL string
+
+	// Method is the HTTP method of the initial request. Empty means GET.
+	Method string
+
+	// Header holds additional headers sent with the initial request. They are
+	// carried across redirects as http.Client normally does.
+	Header http.Header
+
+	// Body is sent as the body of the initial request.
+	Body string
+
+	// Tag is copied to TraceResult.Tag, so that a caller can match results
+	// to its requests even when Options.RedactParams has changed InputURL.
+	Tag string
+}
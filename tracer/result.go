@@ -0,0 +1,394 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Hop is a single request/response exchange within a redirect chain.
+type Hop struct {
+	// URL is the full URL which was requested.
+	URL string `json:"url"`
+
+	// Host is the host portion of URL.
+	Host string `json:"host"`
+
+	// Method is the HTTP method used to request URL.
+	Method string `json:"method"`
+
+	// StatusCode is the HTTP status code returned for URL.
+	StatusCode int `json:"status"`
+
+	// RegisteredDomain is the registered domain (eTLD+1) of Host, such as
+	// example.co.uk for www.a.example.co.uk. It is empty for IP addresses.
+	RegisteredDomain string `json:"registered_domain,omitempty"`
+
+	// RequestID is the value sent in Options.RequestIDHeader, if any.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Duration is the time from sending the request until the response
+	// headers arrived.
+	Duration time.Duration `json:"duration_ns,omitempty"`
+
+	// ProbeDurations holds the Duration of this hop in each run of a trace
+	// repeated with Options.Repeat, in order. A run which did not reach the
+	// hop, having followed a different chain or failed before it, has 0.
+	ProbeDurations []time.Duration `json:"probe_durations_ns,omitempty"`
+
+	// Protocol is the HTTP version of the response, e.g. HTTP/1.1, HTTP/2
+	// or HTTP/3.
+	Protocol string `json:"protocol,omitempty"`
+
+	// TLSVersion is the TLS version negotiated for the hop, e.g. TLS 1.3,
+	// or empty when it was not made over TLS.
+	TLSVersion string `json:"tls_version,omitempty"`
+
+	// HeaderBytes is the size of the response's status line and headers as
+	// HTTP/1.1 would send them. With HTTP/2 fewer bytes cross the wire.
+	HeaderBytes int64 `json:"header_bytes,omitempty"`
+
+	// BodyBytes is the number of response body bytes read. A body which was
+	// not needed, such as that of a HEAD request or of the final response
+	// when nothing inspects it, is not read. http.Client reads at most a few
+	// kilobytes of a redirect's body so that the connection can be reused.
+	BodyBytes int64 `json:"body_bytes,omitempty"`
+
+	// Timing breaks Duration down by phase when Options.Timing is set.
+	Timing *Timing `json:"timing,omitempty"`
+
+	// Location is the raw Location header of a redirect response, exactly as
+	// the server sent it. It may be relative.
+	Location string `json:"location,omitempty"`
+
+	// RedirectURL is Location resolved against URL, i.e. the next hop the
+	// client computed.
+	RedirectURL string `json:"redirect_url,omitempty"`
+
+	// Refresh is set when the next hop was reached by following this hop's
+	// Refresh header or meta refresh.
+	Refresh *Refresh `json:"refresh,omitempty"`
+
+	// Link is set when the next hop was reached by following this hop's Link
+	// header.
+	Link *Link `json:"link,omitempty"`
+
+	// Cached is set when the response was replayed from Options.Cache rather
+	// than fetched.
+	Cached bool `json:"cached,omitempty"`
+
+	// Replayed is set when the response was read from Options.Replay rather
+	// than received live.
+	Replayed bool `json:"replayed,omitempty"`
+
+	// Labels describe notable properties of the redirect which led to this
+	// hop, such as LabelTrailingSlash.
+	Labels []string `json:"labels,omitempty"`
+
+	// ResolvedAddrs holds the addresses the hop's host resolved to. It is
+	// empty when no lookup was needed, e.g. for a reused connection.
+	ResolvedAddrs []string `json:"resolved_addrs,omitempty"`
+
+	// DataURI describes the payload when this hop is a data: URL reached by a
+	// redirect with Options.DecodeDataURI set. Such a hop has no status.
+	DataURI *DataURI `json:"data_uri,omitempty"`
+
+	// WebSocket is set when this hop is a ws: or wss: endpoint reached by a
+	// redirect.
+	WebSocket *WebSocket `json:"websocket,omitempty"`
+
+	// Certificate describes the certificate of an https hop when
+	// Options.CheckCertHostnames, CertExpiryWarn or FailOnCertExpiry is set.
+	Certificate *Certificate `json:"certificate,omitempty"`
+
+	// DNSRecords holds every address the hop's host resolved to, looked up
+	// separately from the connection, and RemoteAddr the address connected
+	// to, when Options.ShowDNS is set. Through a proxy RemoteAddr is the
+	// proxy's address.
+	DNSRecords []string `json:"dns_records,omitempty"`
+	RemoteAddr string   `json:"remote_addr,omitempty"`
+
+	// CNAMEs holds the names the hop's host resolves through by CNAME
+	// records, in order, the last being the canonical name, when
+	// Options.ShowCNAME is set.
+	CNAMEs []string `json:"cnames,omitempty"`
+
+	// Tunnel is the Options.ConnectVia proxy through which a CONNECT tunnel
+	// was established for this hop. It is empty when the hop reused an
+	// earlier hop's connection.
+	Tunnel string `json:"tunnel,omitempty"`
+
+	// Server and Via hold the response's Server header and every Via
+	// header, which name the servers, proxies and CDNs that handled the hop.
+	Server string   `json:"server,omitempty"`
+	Via    []string `json:"via,omitempty"`
+
+	// ETag and LastModified hold the validators returned for a conditional
+	// request, one sent with If-None-Match or If-Modified-Since, whether the
+	// response was 304 Not Modified or the full resource.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// Informational holds any 1xx responses, such as 103 Early Hints, which
+	// arrived before the hop's final response.
+	Informational []Informational `json:"informational,omitempty"`
+
+	// Header holds the response headers when Options.CaptureHeaders is set.
+	Header http.Header `json:"headers,omitempty"`
+
+	// Request holds what was sent for this hop when Options.CaptureRequests
+	// is set.
+	Request *SentRequest `json:"request,omitempty"`
+}
+
+// Informational is a 1xx response received while waiting for a hop's final
+// response.
+type Informational struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"headers,omitempty"`
+}
+
+func newHop(req *http.Request, resp *http.Response) Hop {
+	hop := Hop{
+		URL:         req.URL.String(),
+		Host:        req.URL.Host,
+		Method:      req.Method,
+		StatusCode:  resp.StatusCode,
+		HeaderBytes: headerSize(resp),
+		Server:      resp.Header.Get("Server"),
+		Via:         resp.Header.Values("Via"),
+	}
+
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		hop.ETag = resp.Header.Get("ETag")
+		hop.LastModified = resp.Header.Get("Last-Modified")
+	}
+
+	if isRedirect(resp.StatusCode) {
+		hop.Location = resp.Header.Get("Location")
+		if hop.Location != "" {
+			if next, err := req.URL.Parse(hop.Location); err == nil {
+				hop.RedirectURL = next.String()
+			}
+		}
+	}
+
+	return hop
+}
+
+// headerSize returns the size of resp's status line and headers, including
+// the blank line ending them, as written by HTTP/1.1.
+func headerSize(resp *http.Response) int64 {
+	size := len(resp.Proto) + len(" ") + len(resp.Status) + len("\r\n")
+	for name, values := range resp.Header {
+		for _, value := range values {
+			size += len(name) + len(": ") + len(value) + len("\r\n")
+		}
+	}
+	return int64(size + len("\r\n"))
+}
+
+// isRedirect reports whether code is one of the 3xx statuses which
+// http.Client follows when a Location header is present.
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// TrackingParam is a query parameter which a redirect added to the URL.
+type TrackingParam struct {
+	// Name is the parameter's name.
+	Name string `json:"name"`
+
+	// Hop is the index of the hop whose URL first had the parameter.
+	Hop int `json:"hop"`
+
+	// Known is set when the parameter is a well known tracking parameter,
+	// such as utm_source, fbclid or gclid.
+	Known bool `json:"known,omitempty"`
+}
+
+// TraceResult is the outcome of tracing a single input URL.
+type TraceResult struct {
+	// InputURL is the URL as it was provided to the Tracer.
+	InputURL string `json:"input_url"`
+
+	// TraceID is Options.TraceID, if any.
+	TraceID string `json:"trace_id,omitempty"`
+
+	// Proxy is the proxy the trace went through, with any password redacted.
+	Proxy string `json:"proxy,omitempty"`
+
+	// Hops holds each exchange in the order it occurred. When Err is set, Hops
+	// holds the partial chain collected before the failure.
+	Hops []Hop `json:"hops"`
+
+	// StopReason explains why the chain was deliberately ended before the
+	// redirect was followed. It is empty when the chain ran to completion.
+	StopReason string `json:"stop_reason,omitempty"`
+
+	// BodyMatch is the value extracted from the final response body by
+	// Options.BodyRegex. It is empty when there was no match.
+	BodyMatch string `json:"body_match,omitempty"`
+
+	// Canonical is the final page's <link rel="canonical"> URL, resolved
+	// against the final URL, when Options.CanonicalCheck is set.
+	Canonical string `json:"canonical,omitempty"`
+
+	// FinalURL and CanonicalFinal are the final hop's URL as it was
+	// requested, and in the comparable form described by
+	// Options.CanonicalFinal, when that is set.
+	FinalURL       string `json:"final_url,omitempty"`
+	CanonicalFinal string `json:"canonical_final,omitempty"`
+
+	// TrackingParamsAdded lists the query parameters introduced by each
+	// redirect, which tracking redirectors use to tag the visitor.
+	TrackingParamsAdded []TrackingParam `json:"tracking_params_added,omitempty"`
+
+	// HeaderDiff holds the differences between the response headers of the
+	// first and final hops when Options.CompareHeaders is set.
+	HeaderDiff *HeaderDiff `json:"header_diff,omitempty"`
+
+	// ChainHash is the ChainHash of the result when Options.ChainHash is set.
+	ChainHash string `json:"chain_hash,omitempty"`
+
+	// TotalBytes is the number of response body bytes read across every hop
+	// of the chain.
+	TotalBytes int64 `json:"total_bytes,omitempty"`
+
+	// TotalHeaderBytes is the sum of every hop's HeaderBytes.
+	TotalHeaderBytes int64 `json:"total_header_bytes,omitempty"`
+
+	// Attempts is the number of times the trace was attempted when it had to
+	// be retried.
+	Attempts int `json:"attempts,omitempty"`
+
+	// RetryReasons explains why each retry was made, e.g.
+	// "attempt 1 ended with status 503".
+	RetryReasons []string `json:"retry_reasons,omitempty"`
+
+	// Runs is the number of times the URL was traced when Options.Repeat is
+	// set.
+	Runs int `json:"runs,omitempty"`
+
+	// DistinctChains holds each distinct chain seen across Runs. More than one
+	// entry means the URL redirected inconsistently.
+	DistinctChains []Chain `json:"distinct_chains,omitempty"`
+
+	// Warnings lists notable but non-fatal findings about the chain, such as
+	// a canonical URL mismatch.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Failures lists the policy checks which the chain violated, such as a
+	// slow hop with Options.FailOnSlow set. A trace may complete without error
+	// and still have failures.
+	Failures []string `json:"failures,omitempty"`
+
+	// Err is the error which ended the trace, if any.
+	Err error `json:"-"`
+
+	// Tag is the Tag of the Request traced. It is not part of any output.
+	Tag string `json:"-"`
+}
+
+// Final returns the last hop of the chain, or nil when no hop completed.
+func (r *TraceResult) Final() *Hop {
+	if len(r.Hops) == 0 {
+		return nil
+	}
+	return &r.Hops[len(r.Hops)-1]
+}
+
+// FinalStatus returns the status code of the final hop of result, or 0 when
+// the trace ended without a response.
+func FinalStatus(result *TraceResult) int {
+	final := result.Final()
+	if final == nil {
+		return 0
+	}
+	return final.StatusCode
+}
+
+// FinalScheme returns the lower cased scheme of the final hop's URL, or ""
+// when the trace ended without a response.
+func FinalScheme(result *TraceResult) string {
+	final := result.Final()
+	if final == nil {
+		return ""
+	}
+	u, err := url.Parse(final.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Scheme)
+}
+
+// Consistent reports whether every run of a repeated trace followed the same
+// chain. It is always true for a trace which ran once.
+func (r *TraceResult) Consistent() bool {
+	return len(r.DistinctChains) <= 1
+}
+
+// Normalize returns a copy of r with the values which vary between otherwise
+// identical traces removed or put in order: the trace ID, hop durations and
+// timings and request IDs are cleared and resolved addresses are sorted. Map
+// keys need no treatment as encoding/json always sorts them.
+func Normalize(r *TraceResult) *TraceResult {
+	n := *r
+	n.TraceID = ""
+	n.Hops = make([]Hop, len(r.Hops))
+	for i, hop := range r.Hops {
+		hop.Duration = 0
+		hop.ProbeDurations = nil
+		hop.Timing = nil
+		hop.RequestID = ""
+		if hop.ResolvedAddrs != nil {
+			hop.ResolvedAddrs = append([]string(nil), hop.ResolvedAddrs...)
+			sort.Strings(hop.ResolvedAddrs)
+		}
+		if hop.DNSRecords != nil {
+			hop.DNSRecords = append([]string(nil), hop.DNSRecords...)
+			sort.Strings(hop.DNSRecords)
+		}
+		n.Hops[i] = hop
+	}
+	return &n
+}
+
+// MarshalJSON encodes the result, rendering Err as a string.
+func (r *TraceResult) MarshalJSON() ([]byte, error) {
+	type alias TraceResult
+	var errString string
+	if r.Err != nil {
+		errString = r.Err.Error()
+	}
+
+	return json.Marshal(&struct {
+		*alias
+		Error string `json:"error,omitempty"`
+	}{
+		alias: (*alias)(r),
+		Error: errString,
+	})
+}
@@ -0,0 +1,177 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Default retry delays used when Options leaves them unset.
+const (
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 30 * time.Second
+)
+
+// traceWithRetries traces r, tracing it again up to Options.Retries times
+// while the result is retryable.
+//
+// Before retry n (starting at 1) the Tracer sleeps for a random duration in
+// [0, min(RetryMaxDelay, RetryBaseDelay * 2^(n-1))], the "full jitter"
+// algorithm, so that many clients retrying a struggling server spread out
+// rather than arriving together.
+func (t *Tracer) traceWithRetries(ctx context.Context, r Request) *TraceResult {
+	host := retryHost(r.URL)
+	if t.hostRetriesLeft(host) == 0 {
+		return &TraceResult{InputURL: r.URL, TraceID: t.opts.TraceID, Err: &HostSkippedError{Host: host, Retries: t.opts.MaxRetriesPerHost}}
+	}
+
+	result := t.traceOnce(ctx, r)
+
+	var reasons []string
+	for attempt := 1; attempt <= t.opts.Retries; attempt++ {
+		reason := t.retryReason(ctx, result)
+		if reason == "" {
+			break
+		}
+		if !t.takeHostRetry(host) {
+			reasons = append(reasons, fmt.Sprintf("attempt %d %s, not retried as %s has used its %d retries", attempt, reason, host, t.opts.MaxRetriesPerHost))
+			result.RetryReasons = reasons
+			break
+		}
+		reasons = append(reasons, fmt.Sprintf("attempt %d %s", attempt, reason))
+		if t.opts.DebugLog != nil {
+			t.opts.DebugLog.Debug("retrying", "input_url", r.URL, "attempt", attempt, "reason", reason)
+		}
+
+		select {
+		case <-time.After(t.retryDelay(attempt)):
+		case <-ctx.Done():
+			result.RetryReasons = reasons
+			return result
+		}
+
+		result = t.traceOnce(ctx, r)
+		result.Attempts = attempt + 1
+		result.RetryReasons = reasons
+	}
+
+	return result
+}
+
+// HostSkippedError is the error recorded on a URL which was not traced because
+// its host had used up Options.MaxRetriesPerHost.
+type HostSkippedError struct {
+	Host    string
+	Retries int
+}
+
+func (e *HostSkippedError) Error() string {
+	return fmt.Sprintf("skipped, %s has already used its %d retries", e.Host, e.Retries)
+}
+
+// retryHost returns the lower cased host and any port of the input URL
+// rawURL, or "" if it has none.
+func retryHost(rawURL string) string {
+	u, err := parseInput(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// hostRetriesLeft returns how many more retries host may have, or -1 when
+// there is no limit.
+func (t *Tracer) hostRetriesLeft(host string) int {
+	if t.opts.MaxRetriesPerHost <= 0 || host == "" {
+		return -1
+	}
+	t.hostRetriesMu.Lock()
+	defer t.hostRetriesMu.Unlock()
+	return max(t.opts.MaxRetriesPerHost-t.hostRetries[host], 0)
+}
+
+// takeHostRetry counts a retry against host, reporting false when it has none
+// left.
+func (t *Tracer) takeHostRetry(host string) bool {
+	if t.opts.MaxRetriesPerHost <= 0 || host == "" {
+		return true
+	}
+	t.hostRetriesMu.Lock()
+	defer t.hostRetriesMu.Unlock()
+	if t.hostRetries[host] >= t.opts.MaxRetriesPerHost {
+		return false
+	}
+	if t.hostRetries == nil {
+		t.hostRetries = map[string]int{}
+	}
+	t.hostRetries[host]++
+	return true
+}
+
+// retryDelay returns the jittered delay before the given retry attempt.
+func (t *Tracer) retryDelay(attempt int) time.Duration {
+	base := t.opts.RetryBaseDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	max := t.opts.RetryMaxDelay
+	if max <= 0 {
+		max = DefaultRetryMaxDelay
+	}
+
+	ceiling := max
+	// Stop doubling once the ceiling is reached, which also avoids overflow.
+	if shift := uint(attempt - 1); shift < 32 && base<<shift < max && base<<shift > 0 {
+		ceiling = base << shift
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryReason describes why result failed in a way which may succeed if
+// tried again: a network level error, a 429 Too Many Requests or a final
+// status in Options.RetryOnStatus. It returns "" when result is final.
+func (t *Tracer) retryReason(ctx context.Context, result *TraceResult) string {
+	if isNetworkError(ctx, result) {
+		return fmt.Sprintf("failed: %v", result.Err)
+	}
+
+	final := result.Final()
+	if ctx.Err() != nil || result.Err != nil || final == nil {
+		return ""
+	}
+	if final.StatusCode == http.StatusTooManyRequests || t.opts.RetryOnStatus.Contains(final.StatusCode) {
+		return fmt.Sprintf("ended with status %d", final.StatusCode)
+	}
+	return ""
+}
+
+// isNetworkError reports whether result ended with an error other than one
+// caused by the URL itself or a redirect policy, such as a failure to reach
+// the host or a proxy.
+func isNetworkError(ctx context.Context, result *TraceResult) bool {
+	if ctx.Err() != nil || result.Err == nil {
+		return false
+	}
+
+	var invalid *InvalidURL
+	var policy *PolicyError
+	return !errors.As(result.Err, &invalid) && !errors.As(result.Err, &policy)
+}
@@ -0,0 +1,72 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusSet matches HTTP status codes, either exactly or by class.
+type StatusSet struct {
+	codes   map[int]bool
+	classes map[int]bool
+}
+
+// ParseStatusSet parses status codes such as "404" and classes such as "5xx".
+// Each element may itself hold a comma separated list.
+func ParseStatusSet(specs []string) (*StatusSet, error) {
+	set := &StatusSet{codes: map[int]bool{}, classes: map[int]bool{}}
+
+	for _, spec := range specs {
+		for _, s := range strings.Split(spec, ",") {
+			s = strings.ToLower(strings.TrimSpace(s))
+			if s == "" {
+				continue
+			}
+
+			if len(s) == 3 && strings.HasSuffix(s, "xx") {
+				class, err := strconv.Atoi(s[:1])
+				if err != nil || class < 1 || class > 5 {
+					return nil, fmt.Errorf("invalid status class %q", s)
+				}
+				set.classes[class] = true
+				continue
+			}
+
+			code, err := strconv.Atoi(s)
+			if err != nil || code < 100 || code > 599 {
+				return nil, fmt.Errorf("invalid status code %q", s)
+			}
+			set.codes[code] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Contains reports whether code is in the set. A nil set contains nothing.
+func (s *StatusSet) Contains(code int) bool {
+	if s == nil {
+		return false
+	}
+	return s.codes[code] || s.classes[code/100]
+}
+
+// Empty reports whether the set matches no status codes.
+func (s *StatusSet) Empty() bool {
+	return s == nil || (len(s.codes) == 0 && len(s.classes) == 0)
+}
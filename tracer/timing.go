@@ -0,0 +1,121 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks down where the time of a single hop was spent, in the manner
+// of curl's --write-out timers. Phases which did not happen, such as DNS and
+// connect on a reused connection, are zero.
+type Timing struct {
+	// Started is when the request was sent.
+	Started time.Time `json:"started"`
+
+	DNS     time.Duration `json:"dns_ns"`
+	Connect time.Duration `json:"connect_ns"`
+	TLS     time.Duration `json:"tls_ns"`
+
+	// TTFB is the time from sending the request until the first response
+	// byte arrived.
+	TTFB time.Duration `json:"ttfb_ns"`
+
+	// Total is the time until the response headers were read.
+	Total time.Duration `json:"total_ns"`
+}
+
+// add accumulates t into the receiver.
+func (t *Timing) add(o *Timing) {
+	t.DNS += o.DNS
+	t.Connect += o.Connect
+	t.TLS += o.TLS
+	t.TTFB += o.TTFB
+	t.Total += o.Total
+}
+
+// TotalTiming sums the timings of every hop of result, returning nil when
+// none were recorded.
+func TotalTiming(result *TraceResult) *Timing {
+	var total *Timing
+	for _, hop := range result.Hops {
+		if hop.Timing == nil {
+			continue
+		}
+		if total == nil {
+			total = &Timing{}
+		}
+		total.add(hop.Timing)
+	}
+	return total
+}
+
+// phaseTimer measures the connection phases of a round trip. Dialing happens
+// on its own goroutine, so its callbacks are guarded.
+type phaseTimer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       Timing
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{start: time.Now()}
+}
+
+// hook adds the timer's callbacks to trace.
+func (p *phaseTimer) hook(trace *httptrace.ClientTrace) {
+	since := func(t *time.Time, d *time.Duration) {
+		p.mu.Lock()
+		if !t.IsZero() {
+			*d = time.Since(*t)
+		}
+		p.mu.Unlock()
+	}
+	mark := func(t *time.Time) {
+		p.mu.Lock()
+		*t = time.Now()
+		p.mu.Unlock()
+	}
+
+	dnsDone := trace.DNSDone
+	trace.DNSStart = func(httptrace.DNSStartInfo) { mark(&p.dnsStart) }
+	trace.DNSDone = func(info httptrace.DNSDoneInfo) {
+		since(&p.dnsStart, &p.timing.DNS)
+		if dnsDone != nil {
+			dnsDone(info)
+		}
+	}
+	trace.ConnectStart = func(string, string) { mark(&p.connectStart) }
+	trace.ConnectDone = func(string, string, error) { since(&p.connectStart, &p.timing.Connect) }
+	trace.TLSHandshakeStart = func() { mark(&p.tlsStart) }
+	trace.TLSHandshakeDone = func(tls.ConnectionState, error) { since(&p.tlsStart, &p.timing.TLS) }
+	trace.GotFirstResponseByte = func() { since(&p.start, &p.timing.TTFB) }
+}
+
+// result returns the measured timing, ending the total now.
+func (p *phaseTimer) result() *Timing {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timing := p.timing
+	timing.Started = p.start
+	timing.Total = time.Since(p.start)
+	return &timing
+}
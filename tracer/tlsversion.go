@@ -0,0 +1,76 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TLSVersionError is the error recorded when a hop's TLS handshake failed
+// because the server could not negotiate Options.MinTLSVersion or later.
+type TLSVersionError struct {
+	// Host is the host:port of the hop.
+	Host string
+
+	// MinVersion is the lowest version which was accepted, e.g. TLS 1.2.
+	MinVersion string
+
+	// Err is the handshake error.
+	Err error
+}
+
+func (e *TLSVersionError) Error() string {
+	return fmt.Sprintf("%s could not negotiate %s or later: %v", e.Host, e.MinVersion, e.Err)
+}
+
+func (e *TLSVersionError) Unwrap() error { return e.Err }
+
+// ParseTLSVersion parses a TLS version such as 1.2 or TLS1.2 for
+// Options.MinTLSVersion.
+func ParseTLSVersion(value string) (uint16, error) {
+	version := strings.TrimPrefix(strings.ToLower(strings.ReplaceAll(value, " ", "")), "tls")
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version %q, expected 1.0, 1.1, 1.2 or 1.3", value)
+}
+
+// tlsVersionError returns err as a *TLSVersionError when it reports a failure
+// to agree on a protocol version with host, or err itself otherwise.
+func tlsVersionError(err error, host string, minVersion uint16) error {
+	if minVersion == 0 || !strings.Contains(err.Error(), "protocol version") {
+		return err
+	}
+	return &TLSVersionError{Host: host, MinVersion: tls.VersionName(minVersion), Err: err}
+}
+
+// tlsVersionOf returns the name of the TLS version negotiated for resp, e.g.
+// TLS 1.3, or "" when resp was not received over TLS.
+func tlsVersionOf(resp *http.Response) string {
+	if resp.TLS == nil {
+		return ""
+	}
+	return tls.VersionName(resp.TLS.Version)
+}
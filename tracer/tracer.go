@@ -0,0 +1,552 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracer follows the redirect chain of a URL and records each hop
+// along the way. Results are handed to a Reporter, which decides how they are
+// rendered.
+package tracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/publicsuffix"
+)
+
+// errChainTimeout is the cause of a chain's context expiring after
+// Options.ChainTimeout.
+var errChainTimeout = errors.New("chain timeout exceeded")
+
+// Tracer follows redirect chains using a shared HTTP client.
+type Tracer struct {
+	opts     Options
+	clients  []*proxyClient
+	rotation uint64
+	spans    oteltrace.Tracer
+	redact   *redactor
+
+	// hostRetries counts the retries made for each input host when
+	// Options.MaxRetriesPerHost is set.
+	hostRetriesMu sync.Mutex
+	hostRetries   map[string]int
+}
+
+// New returns a Tracer configured by opts.
+func New(opts Options) (*Tracer, error) {
+	dialer, err := newDialer(opts.DNSServer)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies := opts.Proxies
+	if opts.Proxy != nil {
+		proxies = append([]*url.URL{opts.Proxy}, proxies...)
+	}
+	if opts.UnixSocket != "" && len(proxies) > 0 {
+		return nil, errors.New("a Unix socket cannot be combined with a proxy")
+	}
+	if opts.HTTP3 && (opts.UnixSocket != "" || len(proxies) > 0) {
+		return nil, errors.New("HTTP/3 cannot be combined with a proxy or a Unix socket")
+	}
+	if opts.ConnectVia != "" {
+		if _, _, err := net.SplitHostPort(opts.ConnectVia); err != nil {
+			return nil, fmt.Errorf("invalid CONNECT tunnel address %q, expected host:port", opts.ConnectVia)
+		}
+		if opts.UnixSocket != "" || len(proxies) > 0 || opts.HTTP3 {
+			return nil, errors.New("a CONNECT tunnel cannot be combined with a proxy, a Unix socket or HTTP/3")
+		}
+	}
+	if len(proxies) == 0 {
+		proxies = []*url.URL{nil}
+	}
+	if err := validateHostPatterns(opts.AllowHosts); err != nil {
+		return nil, err
+	}
+	if err := validateHostPatterns(opts.DenyHosts); err != nil {
+		return nil, err
+	}
+
+	t := &Tracer{
+		opts:   opts,
+		spans:  newSpanTracer(opts.TracerProvider),
+		redact: newRedactor(opts),
+	}
+
+	// Each proxy gets its own client and transport so that pooled connections
+	// are never shared between proxies.
+	for _, proxyURL := range proxies {
+		transport, err := newTransport(opts, dialer, proxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapper := &TransportWrapper{
+			Transport:       transport,
+			CaptureHeaders:  opts.CaptureHeaders || opts.CompareHeaders,
+			CaptureRequests: opts.CaptureRequests,
+			RevealSecrets:   opts.RevealSecrets,
+			OnHop:           opts.OnHop,
+			Header:          opts.Header,
+
+			RequestIDHeader: opts.RequestIDHeader,
+			RequestID:       opts.RequestID,
+
+			Spans:         t.spans,
+			redact:        t.redact,
+			Cache:         opts.Cache,
+			Dump:          opts.Dump,
+			Replay:        opts.Replay,
+			MaxTotalBytes: opts.MaxTotalBytes,
+			Timing:        opts.Timing,
+			Punycode:      opts.Punycode,
+			Certificates:  opts.CheckCertHostnames || opts.CertExpiryWarn > 0 || opts.FailOnCertExpiry,
+			MinTLSVersion: opts.MinTLSVersion,
+			DNSRecords:    opts.ShowDNS,
+			CNAMEs:        opts.ShowCNAME,
+			DNSServer:     opts.DNSServer,
+			DebugLog:      opts.DebugLog,
+			Resolver:      dialer.Resolver,
+		}
+		if opts.HTTP3 {
+			wrapper.HTTP3 = newHTTP3Transport(transport, opts.MaxHeaderBytes)
+		}
+
+		t.clients = append(t.clients, &proxyClient{
+			proxy: proxyURL,
+			Client: &http.Client{
+				Transport:     wrapper,
+				Timeout:       opts.Timeout,
+				CheckRedirect: t.checkRedirect,
+			},
+		})
+	}
+
+	return t, nil
+}
+
+// proxyClient is a client whose requests go through proxy, if set.
+type proxyClient struct {
+	*http.Client
+	proxy *url.URL
+}
+
+// nextClient returns the index of the client the next trace should use.
+func (t *Tracer) nextClient() int {
+	switch {
+	case len(t.clients) == 1:
+		return 0
+	case t.opts.ProxyRotation == ProxyRandom:
+		return rand.Intn(len(t.clients))
+	default:
+		return int((atomic.AddUint64(&t.rotation, 1) - 1) % uint64(len(t.clients)))
+	}
+}
+
+// maxRedirects mirrors the limit applied by http.Client's default policy.
+const maxRedirects = 10
+
+// redirectPolicy decides whether the client follows the redirect to req. via
+// holds the requests made so far, oldest first.
+func (t *Tracer) redirectPolicy(req *http.Request, via []*http.Request) error {
+	rec := recorderFrom(req.Context())
+	stop := func(reason string) error {
+		if rec != nil {
+			rec.stopReason = reason
+		}
+		return http.ErrUseLastResponse
+	}
+
+	if code := req.Response.StatusCode; t.opts.StopOnStatus.Contains(code) {
+		return stop(stoppedOnStatus(code, len(via)-1, via[len(via)-1].URL.String()))
+	}
+
+	var jar http.CookieJar
+	if rec != nil {
+		jar = rec.jar
+	}
+	if err := checkLoop(req, via, jar); err != nil {
+		return err
+	}
+
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	if err := checkURLLength(req.URL.String(), len(via)-1, t.opts.MaxURLLength); err != nil {
+		return err
+	}
+
+	if t.opts.DecodeDataURI && strings.EqualFold(req.URL.Scheme, "data") {
+		return t.stopAtDataURI(req, rec, stop)
+	}
+
+	// A WebSocket endpoint is the end of the chain, whether or not the
+	// handshake is attempted.
+	if isWebSocketScheme(req.URL.Scheme) {
+		if !t.opts.ProbeWebSocket || req.Method != http.MethodGet {
+			if rec != nil {
+				rec.hops = append(rec.hops, Hop{URL: req.URL.String(), WebSocket: &WebSocket{}})
+			}
+			return stop("redirected to a WebSocket endpoint, the upgrade handshake was not attempted")
+		}
+		if err := prepareWebSocketProbe(req); err != nil {
+			return err
+		}
+	}
+
+	if err := validateURL(req.URL); err != nil {
+		return err
+	}
+	if err := toASCIIHost(req.URL); err != nil {
+		return &InvalidURL{URL: req.URL.String(), Reason: "invalid internationalized host: " + err.Error()}
+	}
+
+	if err := checkHost(req.URL.Hostname(), fmt.Sprintf("redirect from hop %d is to", len(via)-1), t.opts.AllowHosts, t.opts.DenyHosts); err != nil {
+		return err
+	}
+
+	if code := req.Response.StatusCode; !t.opts.FollowStatuses.Empty() && !t.opts.FollowStatuses.Contains(code) {
+		return stop(fmt.Sprintf("%d redirect to %s not followed, status is not in the follow list", code, req.URL))
+	}
+
+	if t.opts.AllowOneUpgrade {
+		if err := checkUpgrade(req, via); err != nil {
+			return err
+		}
+	}
+
+	if prev := via[len(via)-1]; t.opts.StrictMethod && req.Method != prev.Method {
+		return &PolicyError{Reason: fmt.Sprintf("redirect from hop %d (%s) would change the method from %s to %s, use 307 or 308 to preserve it", len(via)-1, prev.URL, prev.Method, req.Method)}
+	}
+
+	if t.opts.SameHostOnly && !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+		return stop(fmt.Sprintf("redirect leaves %s, would have gone to %s", via[0].URL.Host, req.URL))
+	}
+
+	if t.opts.FollowFunc != nil && !t.opts.FollowFunc(via[len(via)-1], req) {
+		return stop(fmt.Sprintf("redirect to %s not followed, rejected by the follow policy", req.URL))
+	}
+
+	return nil
+}
+
+// stoppedOnStatus is the StopReason of a chain ended by Options.StopOnStatus
+// at hop, which requested rawURL.
+func stoppedOnStatus(code, hop int, rawURL string) string {
+	return fmt.Sprintf("on configured status %d at hop %d (%s)", code, hop, rawURL)
+}
+
+// checkUpgrade returns a *PolicyError unless the redirect to req keeps the
+// scheme or is the chain's first change of scheme, from http to https.
+func checkUpgrade(req *http.Request, via []*http.Request) error {
+	prev := via[len(via)-1]
+	from, to := strings.ToLower(prev.URL.Scheme), strings.ToLower(req.URL.Scheme)
+	if from == to {
+		return nil
+	}
+
+	upgraded := false
+	for i := 1; i < len(via); i++ {
+		if !strings.EqualFold(via[i-1].URL.Scheme, via[i].URL.Scheme) {
+			upgraded = true
+		}
+	}
+	if from == "http" && to == "https" && !upgraded {
+		return nil
+	}
+
+	return &PolicyError{Reason: fmt.Sprintf("redirect from hop %d (%s) changes the scheme from %s to %s, only a single http to https upgrade is allowed", len(via)-1, prev.URL, from, to)}
+}
+
+// stopAtDataURI ends the chain at a redirect to a data: URL, recording its
+// decoded payload as the final hop.
+func (t *Tracer) stopAtDataURI(req *http.Request, rec *recorder, stop func(string) error) error {
+	limit := t.opts.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+
+	raw := req.URL.String()
+	data, err := decodeDataURI(raw, limit)
+	if err != nil {
+		return &InvalidURL{URL: raw, Reason: err.Error()}
+	}
+	if rec != nil {
+		rec.hops = append(rec.hops, Hop{URL: raw, DataURI: data})
+	}
+	return stop("redirected to a data: URI, which has no further hops")
+}
+
+// Run traces each URL in turn, passing every result to the configured
+// Reporter. It stops early only if the Reporter fails.
+func (t *Tracer) Run(ctx context.Context, urls []string) error {
+	reqs := make([]Request, len(urls))
+	for i, u := range urls {
+		reqs[i] = Request{URL: u}
+	}
+	return t.RunRequests(ctx, reqs)
+}
+
+// RunRequests is like Run but traces each Request with its own settings.
+func (t *Tracer) RunRequests(ctx context.Context, reqs []Request) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var runErr error
+	for i, r := range reqs {
+		result := t.TraceRequest(ctx, r)
+		if t.opts.Reporter != nil {
+			if err := t.opts.Reporter.Report(result); err != nil {
+				return err
+			}
+		}
+
+		if t.opts.FailFast && (result.Err != nil || len(result.Failures) > 0) {
+			cancel()
+			runErr = &FailFastError{URL: r.URL, Skipped: len(reqs) - i - 1}
+			break
+		}
+	}
+
+	if t.opts.Reporter != nil {
+		if err := t.opts.Reporter.Flush(); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
+
+// Trace follows the redirect chain of rawURL. URLs without a scheme are
+// assumed to be http, and only http and https URLs may be traced; others
+// produce an *InvalidURL error. Failures are recorded on the returned result
+// rather than returned separately so that the partial chain is never lost.
+func (t *Tracer) Trace(ctx context.Context, rawURL string) *TraceResult {
+	return t.TraceRequest(ctx, Request{URL: rawURL})
+}
+
+// TraceRequest is like Trace but starts the chain with the method, headers and
+// body described by r.
+func (t *Tracer) TraceRequest(ctx context.Context, r Request) *TraceResult {
+	var result *TraceResult
+	if t.opts.WorkerTimeout > 0 {
+		result = t.traceAbandoning(ctx, r, t.traceRequest)
+	} else {
+		result = t.traceRequest(ctx, r)
+	}
+	if t.redact != nil {
+		t.redact.result(result)
+	}
+	result.Tag = r.Tag
+	return result
+}
+
+// traceRequest traces r as configured, without Options.WorkerTimeout.
+func (t *Tracer) traceRequest(ctx context.Context, r Request) *TraceResult {
+	if t.opts.Repeat > 1 {
+		return t.traceRepeated(ctx, r, t.opts.Repeat)
+	}
+	return t.traceWithRetries(ctx, r)
+}
+
+// traceOnce follows the redirect chain of r a single time, moving on to the
+// following proxies after a network error when Options.ProxyFailover is set.
+func (t *Tracer) traceOnce(ctx context.Context, r Request) *TraceResult {
+	i := t.nextClient()
+	result := t.traceWith(ctx, r, t.clients[i])
+
+	if t.opts.ProxyFailover {
+		for tried := 1; tried < len(t.clients) && isNetworkError(ctx, result); tried++ {
+			i = (i + 1) % len(t.clients)
+			result = t.traceWith(ctx, r, t.clients[i])
+		}
+	}
+
+	return result
+}
+
+// traceWith follows the redirect chain of r using client.
+func (t *Tracer) traceWith(ctx context.Context, r Request, client *proxyClient) *TraceResult {
+	result := &TraceResult{InputURL: r.URL, TraceID: t.opts.TraceID}
+	if client.proxy != nil {
+		result.Proxy = client.proxy.Redacted()
+	}
+
+	// Deferred first so the span ends only once the result is assembled.
+	ctx, span := t.spans.Start(ctx, traceSpanName, oteltrace.WithAttributes(attribute.String("urltrace.input_url", t.redact.url(r.URL))))
+	defer endTraceSpan(span, result, t.redact)
+
+	if log := t.opts.DebugLog; log != nil {
+		log.Debug("trace started", "input_url", r.URL, "method", r.Method, "proxy", result.Proxy)
+		defer func() {
+			log.Debug("trace ended", "input_url", r.URL, "hops", len(result.Hops), "stop_reason", result.StopReason, "failures", result.Failures, "error", result.Err)
+		}()
+	}
+
+	parsedURL, err := parseInput(r.URL)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if err := checkHost(parsedURL.Hostname(), "input URL host is", t.opts.AllowHosts, t.opts.DenyHosts); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if t.opts.ChainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, t.opts.ChainTimeout, errChainTimeout)
+		defer cancel()
+	}
+
+	rec := &recorder{initial: parsedURL}
+	httpClient := client.Client
+	if t.opts.EnableCookies {
+		// Each trace starts with no cookies. The copy shares the transport,
+		// and so its connections.
+		rec.jar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		c := *client.Client
+		c.Jar = rec.jar
+		httpClient = &c
+	}
+	defer func() {
+		result.Hops = rec.hops
+		result.StopReason = rec.stopReason
+		result.TotalBytes = atomic.LoadInt64(&rec.bytes)
+		for i, read := range rec.hopBytes {
+			result.Hops[i].BodyBytes = atomic.LoadInt64(read)
+		}
+		for _, hop := range result.Hops {
+			result.TotalHeaderBytes += hop.HeaderBytes
+		}
+		analyze(result, t.opts)
+		if final := result.Final(); final != nil && final.DataURI == nil && t.opts.CanonicalFinal {
+			result.FinalURL = final.URL
+			result.CanonicalFinal = canonicalURL(final.URL, t.opts.CanonicalSortQuery)
+		}
+		if t.opts.ChainHash {
+			result.ChainHash = ChainHash(result, t.opts.ChainHashStatuses)
+		}
+		if t.opts.CompareHeaders && len(result.Hops) > 1 {
+			result.HeaderDiff = diffHeaders(result.Hops[0].Header, result.Final().Header)
+		}
+	}()
+
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var reqBody io.Reader
+	if r.Body != "" {
+		reqBody = strings.NewReader(r.Body)
+	}
+
+	req, err := http.NewRequest(method, parsedURL.String(), reqBody)
+	if err != nil {
+		result.Err = fmt.Errorf("error creating request: %v", err)
+		return result
+	}
+	for name, values := range r.Header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	ctx = withRecorder(ctx, rec)
+	resp, err := httpClient.Do(req.WithContext(ctx))
+
+	// Each response is read, when needed, and closed here, moving on to any
+	// refresh it asks for.
+	var body []byte
+	var bodyErr error
+	for err == nil {
+		// An upgraded connection has no body to read, only frames.
+		upgraded := resp.StatusCode == http.StatusSwitchingProtocols
+		if !upgraded && (t.needsBody() || (t.opts.FollowMetaRefresh && isHTML(resp))) {
+			body, bodyErr = t.readBody(resp)
+		}
+		resp.Body.Close()
+		if bodyErr != nil {
+			break
+		}
+
+		var next *http.Request
+		next, err = t.nextRefresh(ctx, resp, body, rec)
+		if err == nil && next == nil {
+			next, err = t.nextLink(ctx, resp, rec)
+		}
+		if err != nil || next == nil {
+			break
+		}
+		resp, err = httpClient.Do(next)
+		body = nil
+	}
+
+	var invalid *InvalidURL
+	var policy *PolicyError
+	var loop *LoopError
+	switch {
+	case errors.As(err, &invalid):
+		result.Err = invalid
+	case errors.As(err, &policy):
+		result.Err = policy
+	case errors.As(err, &loop):
+		result.Err = loop
+	case err == nil:
+		if bodyErr != nil {
+			result.Err = bodyErr
+		} else if t.needsBody() {
+			t.inspectBody(result, resp.Request.URL, body)
+		}
+		// http.Client treats a redirect without a Location as the final
+		// response, which is almost always a server misconfiguration.
+		if isRedirect(resp.StatusCode) && resp.Header.Get("Location") == "" && rec.stopReason == "" {
+			rec.stopReason = fmt.Sprintf("%d with no Location header (chain ended)", resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusNotModified && rec.stopReason == "" {
+			rec.stopReason = "304 Not Modified, the validators sent are current (chain ended)"
+		}
+		if t.opts.StopOnStatus.Contains(resp.StatusCode) && rec.stopReason == "" {
+			rec.stopReason = stoppedOnStatus(resp.StatusCode, len(rec.hops)-1, resp.Request.URL.String())
+		}
+	case errors.Is(err, context.DeadlineExceeded) && context.Cause(ctx) == errChainTimeout:
+		// A request's own Timeout also ends in DeadlineExceeded, so only
+		// the expiry of the chain's context is reported as the chain timeout.
+		result.Err = fmt.Errorf("chain timeout of %s exceeded after %d hop(s)", t.opts.ChainTimeout, len(rec.hops))
+	case t.opts.MaxHeaderBytes > 0 && strings.Contains(err.Error(), "server response headers exceeded"):
+		// net/http does not export this error, so it is matched by text.
+		hopURL := parsedURL.String()
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			hopURL = urlErr.URL
+		}
+		result.Err = fmt.Errorf("hop %d (%s) sent response headers exceeding the %d byte limit", len(rec.hops), hopURL, t.opts.MaxHeaderBytes)
+	case errors.Is(err, io.EOF):
+		result.Err = fmt.Errorf("site could not be reached. %v", err)
+	default:
+		result.Err = fmt.Errorf("error when searching for URL: %v", err)
+	}
+
+	return result
+}
@@ -0,0 +1,510 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceRedirectWithoutLocation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/broken", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusFound)
+	}))
+	defer srv.Close()
+
+	tr, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tr.Trace(context.Background(), srv.URL+"/start")
+
+	if result.Err != nil {
+		t.Fatalf("Err = %v, want nil", result.Err)
+	}
+	if len(result.Hops) != 2 {
+		t.Fatalf("got %d hops, want 2", len(result.Hops))
+	}
+	final := result.Final()
+	if final.StatusCode != http.StatusFound || final.URL != srv.URL+"/broken" {
+		t.Errorf("final hop = %d %s, want 302 %s/broken", final.StatusCode, final.URL, srv.URL)
+	}
+	if final.Location != "" || final.RedirectURL != "" {
+		t.Errorf("final hop Location = %q, RedirectURL = %q, want both empty", final.Location, final.RedirectURL)
+	}
+	if want := "302 with no Location header (chain ended)"; result.StopReason != want {
+		t.Errorf("StopReason = %q, want %q", result.StopReason, want)
+	}
+}
+
+func TestEmulatedBrowserBodyIsReadable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Prefer brotli, as many servers do, when the client accepts it.
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte{0x0b, 0x02, 0x80})
+			return
+		}
+		w.Write([]byte("<title>ok</title>"))
+	}))
+	defer srv.Close()
+
+	for _, name := range BrowserNames() {
+		t.Run(name, func(t *testing.T) {
+			header, err := BrowserHeader(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr, err := New(Options{Header: header, BodyRegex: regexp.MustCompile(`<title>(.*)</title>`)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := tr.Trace(context.Background(), srv.URL)
+			if result.Err != nil {
+				t.Fatalf("Err = %v, want nil", result.Err)
+			}
+			if result.BodyMatch != "ok" {
+				t.Errorf("BodyMatch = %q, want ok", result.BodyMatch)
+			}
+		})
+	}
+}
+
+func TestNormalizeIsByteStable(t *testing.T) {
+	var delay time.Duration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/end", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte("done"))
+	}))
+	defer srv.Close()
+
+	var runs [][]byte
+	for i, traceID := range []string{"first", "second"} {
+		delay = time.Duration(i*20) * time.Millisecond
+		tr, err := New(Options{TraceID: traceID, RequestIDHeader: "X-Request-ID", Timing: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := tr.Trace(context.Background(), srv.URL+"/start")
+		if result.Err != nil {
+			t.Fatalf("run %d: %v", i, result.Err)
+		}
+		if result.Hops[0].RequestID == "" || result.Hops[0].Timing == nil {
+			t.Fatalf("run %d recorded no request ID or timing to normalize", i)
+		}
+
+		encoded, err := json.Marshal(Normalize(result))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, encoded)
+	}
+
+	if !bytes.Equal(runs[0], runs[1]) {
+		t.Errorf("normalized traces differ:\n%s\n%s", runs[0], runs[1])
+	}
+}
+
+// BenchmarkRun traces a batch of redirecting URLs spread over a few hosts, as
+// the CLI does, with the idle connection pool tuned as the README suggests
+// and with keep-alives off for comparison.
+func BenchmarkRun(b *testing.B) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/end", http.StatusFound)
+			return
+		}
+		w.Write([]byte("done"))
+	})
+	var urls []string
+	for i := 0; i < 4; i++ {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+		for j := 0; j < 25; j++ {
+			urls = append(urls, fmt.Sprintf("%s/start?n=%d", srv.URL, j))
+		}
+	}
+
+	tuned := http.DefaultTransport.(*http.Transport).Clone()
+	tuned.MaxIdleConns = 1000
+	tuned.MaxIdleConnsPerHost = 2
+	tuned.IdleConnTimeout = 2 * time.Minute
+	noKeepAlive := http.DefaultTransport.(*http.Transport).Clone()
+	noKeepAlive.DisableKeepAlives = true
+
+	for _, bm := range []struct {
+		name      string
+		transport *http.Transport
+	}{
+		{"tuned", tuned},
+		{"no-keep-alive", noKeepAlive},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			tr, err := New(Options{Transport: bm.transport})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := tr.Run(context.Background(), urls); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderCredentialsStayOnInputDomain(t *testing.T) {
+	received := map[string]http.Header{}
+	record := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			received[name+r.URL.Path] = r.Header.Clone()
+		}
+	}
+
+	other := httptest.NewServer(record("other"))
+	defer other.Close()
+	otherURL, _ := url.Parse(other.URL)
+	// Both servers listen on 127.0.0.1, so the other is reached as localhost
+	// to give it a different host.
+	otherURL.Host = "localhost:" + otherURL.Port()
+
+	input := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("input")(w, r)
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/again", http.StatusFound)
+		case "/again":
+			http.Redirect(w, r, otherURL.String()+"/end", http.StatusFound)
+		}
+	}))
+	defer input.Close()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Cookie", "session=secret")
+	header.Set("X-Trace", "1")
+	tr, err := New(Options{Header: header})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tr.Trace(context.Background(), input.URL+"/start")
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if len(result.Hops) != 3 {
+		t.Fatalf("got %d hops, want 3", len(result.Hops))
+	}
+
+	for _, path := range []string{"input/start", "input/again"} {
+		if got := received[path].Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("%s: Authorization = %q, want it sent to the input host", path, got)
+		}
+	}
+	otherHeader := received["other/end"]
+	for _, name := range []string{"Authorization", "Cookie"} {
+		if got := otherHeader.Get(name); got != "" {
+			t.Errorf("other host received %s: %q", name, got)
+		}
+	}
+	if got := otherHeader.Get("X-Trace"); got != "1" {
+		t.Errorf("other host X-Trace = %q, want 1", got)
+	}
+}
+
+// checkFollowedCredentials traces a chain whose input host sends the client
+// on to another host with send, and fails t if the other host received the
+// -H credentials.
+func checkFollowedCredentials(t *testing.T, opts Options, send func(w http.ResponseWriter, target string)) {
+	t.Helper()
+	var otherHeader http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherHeader = r.Header.Clone()
+	}))
+	defer other.Close()
+	otherURL, _ := url.Parse(other.URL)
+	otherURL.Host = "localhost:" + otherURL.Port()
+
+	var inputAuth string
+	input := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inputAuth = r.Header.Get("Authorization")
+		send(w, otherURL.String()+"/x")
+	}))
+	defer input.Close()
+
+	opts.Header = http.Header{}
+	opts.Header.Set("Authorization", "Bearer secret")
+	opts.Header.Set("Cookie", "session=secret")
+	tr, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tr.Trace(context.Background(), input.URL)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if len(result.Hops) != 2 {
+		t.Fatalf("got %d hops, want 2", len(result.Hops))
+	}
+	if inputAuth != "Bearer secret" {
+		t.Errorf("input host Authorization = %q, want it sent", inputAuth)
+	}
+	for _, name := range []string{"Authorization", "Cookie"} {
+		if got := otherHeader.Get(name); got != "" {
+			t.Errorf("other host received %s: %q", name, got)
+		}
+	}
+}
+
+func TestRefreshCredentialsStayOnInputDomain(t *testing.T) {
+	checkFollowedCredentials(t, Options{FollowMetaRefresh: true}, func(w http.ResponseWriter, target string) {
+		w.Header().Set("Refresh", "0; url="+target)
+	})
+}
+
+func TestLinkCredentialsStayOnInputDomain(t *testing.T) {
+	checkFollowedCredentials(t, Options{FollowLinkRels: []string{"next"}}, func(w http.ResponseWriter, target string) {
+		w.Header().Set("Link", "<"+target+">; rel=next")
+	})
+}
+
+func TestSendsSensitiveHeaders(t *testing.T) {
+	initial, _ := url.Parse("https://example.com/start")
+	for dest, want := range map[string]bool{
+		"https://example.com/next":      true,
+		"http://example.com:8080/":      true,
+		"https://api.example.com/":      true,
+		"https://EXAMPLE.com/":          true,
+		"https://notexample.com/":       false,
+		"https://example.com.evil.net/": false,
+	} {
+		u, _ := url.Parse(dest)
+		if got := sendsSensitiveHeaders(initial, u); got != want {
+			t.Errorf("sendsSensitiveHeaders(%s) = %v, want %v", dest, got, want)
+		}
+	}
+}
+
+func TestSpansAreRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/end?token=secret", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tr, err := New(Options{TracerProvider: provider, RedactParams: DefaultRedactParams})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := tr.Trace(context.Background(), srv.URL+"/start?token=secret"); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want a trace span and 2 hop spans", len(spans))
+	}
+	for _, span := range spans {
+		for _, attr := range span.Attributes() {
+			if strings.Contains(attr.Value.Emit(), "secret") {
+				t.Errorf("span %s attribute %s = %q, want it redacted", span.Name(), attr.Key, attr.Value.Emit())
+			}
+		}
+	}
+}
+
+func TestResultIsRedacted(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "<"+srvURL+"/style.css?token=s3cr3t>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		if r.URL.Path == "/start" {
+			w.Header().Set("Content-Location", "/start?token=s3cr3t")
+			http.Redirect(w, r, "/end?utm_source=x&token=s3cr3t", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Location", "/end?token=s3cr3t&v=2")
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	tr, err := New(Options{CompareHeaders: true, RedactParams: DefaultRedactParams})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tr.Trace(context.Background(), srv.URL+"/start")
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.HeaderDiff == nil || len(result.Hops[0].Informational) == 0 {
+		t.Fatal("want a header diff and early hints to check")
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("s3cr3t")) {
+		t.Errorf("secret found in JSON output: %s", out)
+	}
+}
+
+func TestChainTimeoutIsNotRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		chainTimeout time.Duration
+		chain        bool
+	}{
+		{"request timeout", 100 * time.Millisecond, 10 * time.Second, false},
+		{"chain timeout", 10 * time.Second, 100 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := New(Options{Timeout: tt.timeout, ChainTimeout: tt.chainTimeout})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := tr.Trace(context.Background(), srv.URL)
+			if result.Err == nil {
+				t.Fatal("trace did not time out")
+			}
+			if got := strings.Contains(result.Err.Error(), "chain timeout"); got != tt.chain {
+				t.Errorf("error %q, want chain timeout reported: %v", result.Err, tt.chain)
+			}
+		})
+	}
+}
+
+func TestAbandonedTraceDoesNotOverlapTheNext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/end", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	var active, overlapped int32
+	results := &collectReporter{}
+	tr, err := New(Options{
+		WorkerTimeout: 50 * time.Millisecond,
+		Reporter:      results,
+		// A policy which is slow and ignores cancellation keeps the first
+		// trace running past its worker timeout.
+		FollowFunc: func(prev, next *http.Request) bool {
+			if atomic.AddInt32(&active, 1) > 1 {
+				atomic.StoreInt32(&overlapped, 1)
+			}
+			time.Sleep(200 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			return true
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(context.Background(), []string{srv.URL + "/start", srv.URL + "/start"}); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&overlapped) != 0 {
+		t.Error("FollowFunc ran for two traces at once")
+	}
+	if len(results.results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results.results))
+	}
+	for i, result := range results.results {
+		var abandoned *AbandonedError
+		if !errors.As(result.Err, &abandoned) {
+			t.Errorf("result %d Err = %v, want *AbandonedError", i, result.Err)
+		}
+	}
+}
+
+func TestAbandonedDuringRetryBackoff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	tr, err := New(Options{
+		WorkerTimeout:  50 * time.Millisecond,
+		Retries:        3,
+		RetryBaseDelay: 10 * time.Second,
+		RetryMaxDelay:  10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tr.Trace(context.Background(), srv.URL)
+
+	var abandoned *AbandonedError
+	if !errors.As(result.Err, &abandoned) {
+		t.Fatalf("Err = %v, want *AbandonedError", result.Err)
+	}
+	if final := result.Final(); final == nil || final.StatusCode != http.StatusTooManyRequests {
+		t.Error("abandoned trace lost its last response")
+	}
+}
+
+// collectReporter keeps every result.
+type collectReporter struct {
+	results []*TraceResult
+}
+
+func (c *collectReporter) Report(result *TraceResult) error {
+	c.results = append(c.results, result)
+	return nil
+}
+func (c *collectReporter) Flush() error { return nil }
+
+// discardReporter drops every result.
+type discardReporter struct{}
+
+func (discardReporter) Report(*TraceResult) error { return nil }
+func (discardReporter) Flush() error              { return nil }
@@ -0,0 +1,465 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// TransportWrapper wraps the http.Transport structure to allow us to record the
+// URLs which we are redirected through
+type TransportWrapper struct {
+	*http.Transport
+
+	// CaptureHeaders records the response headers on each hop.
+	CaptureHeaders bool
+
+	// CaptureRequests records the request sent on each hop, redacting
+	// credentials unless RevealSecrets is set.
+	CaptureRequests bool
+	RevealSecrets   bool
+
+	// OnHop is invoked with each hop as it is recorded.
+	OnHop func(Hop)
+
+	// Header is added to every request which does not already set the same
+	// header. Its Authorization, WWW-Authenticate and Cookie headers are only
+	// sent to the input URL's host and its subdomains.
+	Header http.Header
+
+	// RequestIDHeader, when set, names a header carrying RequestID, or a new
+	// UUID when RequestID is empty, on each request.
+	RequestIDHeader string
+	RequestID       string
+
+	// Spans starts an OpenTelemetry span for each round trip. When nil no
+	// spans are recorded.
+	Spans oteltrace.Tracer
+
+	// redact masks the URLs and errors given to Spans.
+	redact *redactor
+
+	// Cache, when set, replays fresh stored responses in place of network
+	// requests and stores cacheable new ones.
+	Cache *Cache
+
+	// Dump, when set, records every response received. Replay, when set,
+	// answers every request from its recorded responses and nothing is sent.
+	Dump   *Dump
+	Replay *Dump
+
+	// MaxTotalBytes, when positive, limits the response body bytes read
+	// across every hop of a trace.
+	MaxTotalBytes int64
+
+	// Timing records a breakdown of where each hop's time was spent.
+	Timing bool
+
+	// Punycode records internationalized hosts in their punycode form rather
+	// than in Unicode.
+	Punycode bool
+
+	// DebugLog, when set, receives every request sent and response received
+	// at debug level.
+	DebugLog *slog.Logger
+
+	// Certificates records the leaf certificate of each https hop.
+	Certificates bool
+
+	// MinTLSVersion is the lowest TLS version the transport accepts, if set.
+	// Handshakes failing for want of it return a *TLSVersionError.
+	MinTLSVersion uint16
+
+	// DNSRecords looks up every address of each hop's host with Resolver,
+	// or net.DefaultResolver when nil, and records the address connected to.
+	DNSRecords bool
+	Resolver   *net.Resolver
+
+	// CNAMEs looks up the CNAME chain of each hop's host by asking
+	// DNSServer, or only its canonical name with Resolver when empty.
+	CNAMEs    bool
+	DNSServer string
+
+	// HTTP3, when set, is tried first for https requests. Hosts it cannot
+	// reach are remembered and sent to Transport instead.
+	HTTP3    http.RoundTripper
+	fallback http3Fallback
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *TransportWrapper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Use the default transport for this function, we only want to do this for
+	// recording purposes, not adjusting the transport itself.
+	transport := t.Transport
+
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+
+	spans := t.Spans
+	if spans == nil {
+		spans = newSpanTracer(nil)
+	}
+	ctx, span := spans.Start(req.Context(), hopSpanName, oteltrace.WithAttributes(
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		attribute.String("url.full", t.redact.url(req.URL.String())),
+	))
+	defer span.End()
+
+	info := &hopTrace{}
+	clientTrace := info.clientTrace()
+	var timer *phaseTimer
+	if t.Timing {
+		timer = newPhaseTimer()
+		timer.hook(clientTrace)
+	}
+	ctx = context.WithValue(ctx, hopTraceKey{}, info)
+	req = req.WithContext(httptrace.WithClientTrace(ctx, clientTrace))
+
+	rec := recorderFrom(req.Context())
+
+	// The request is shared with http.Client, so headers are added to a copy.
+	// Credentials are kept from hosts outside the input URL's domain, which
+	// http.Client would strip them for on a redirect.
+	if len(t.Header) > 0 || t.RequestIDHeader != "" {
+		req.Header = req.Header.Clone()
+		var initial *url.URL
+		if rec != nil {
+			initial = rec.initial
+		}
+		sensitive := sendsSensitiveHeaders(initial, req.URL)
+		for name, values := range t.Header {
+			if !sensitive && redirectSensitiveHeaders[http.CanonicalHeaderKey(name)] {
+				continue
+			}
+			if _, ok := req.Header[name]; !ok {
+				req.Header[name] = values
+			}
+		}
+	}
+
+	var requestID string
+	if t.RequestIDHeader != "" {
+		requestID = t.RequestID
+		if requestID == "" {
+			var err error
+			if requestID, err = newUUID(); err != nil {
+				return nil, fmt.Errorf("error generating request ID: %v", err)
+			}
+		}
+		req.Header.Set(t.RequestIDHeader, requestID)
+	}
+
+	if rec != nil && t.MaxTotalBytes > 0 && atomic.LoadInt64(&rec.bytes) > t.MaxTotalBytes {
+		return nil, budgetError(t.MaxTotalBytes)
+	}
+
+	if t.DebugLog != nil {
+		logRequest(t.DebugLog, req, t.RevealSecrets)
+	}
+
+	start := time.Now()
+	var resp *http.Response
+	var err error
+	cached := false
+	replayed := t.Replay != nil
+	if replayed {
+		if resp, err = t.Replay.replay(req); err != nil {
+			spanErr := t.redact.err(err)
+			span.RecordError(spanErr)
+			span.SetStatus(codes.Error, spanErr.Error())
+			return nil, err
+		}
+	} else if t.Cache != nil {
+		resp = t.Cache.get(req)
+		cached = resp != nil
+	}
+	if !cached && !replayed {
+		if t.HTTP3 != nil {
+			resp, err = t.fallback.roundTrip(t.HTTP3, transport, req)
+		} else {
+			resp, err = transport.RoundTrip(req)
+		}
+		if err != nil {
+			err = tlsVersionError(err, req.URL.Host, t.MinTLSVersion)
+			if t.DebugLog != nil {
+				logResponse(t.DebugLog, req, nil, err, "network")
+			}
+			spanErr := t.redact.err(err)
+			span.RecordError(spanErr)
+			span.SetStatus(codes.Error, spanErr.Error())
+			return resp, err
+		}
+		if t.Cache != nil {
+			t.Cache.put(req, resp)
+		}
+		if t.Dump != nil {
+			t.Dump.record(req, resp)
+		}
+	}
+	if t.DebugLog != nil {
+		source := "network"
+		if cached {
+			source = "cache"
+		} else if replayed {
+			source = "replay"
+		}
+		logResponse(t.DebugLog, req, resp, nil, source)
+	}
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", resp.StatusCode),
+		attribute.Int64("urltrace.hop.duration_ms", time.Since(start).Milliseconds()),
+	)
+
+	if rec != nil {
+		read := new(int64)
+		if rec.hopBytes == nil {
+			rec.hopBytes = map[int]*int64{}
+		}
+		rec.hopBytes[len(rec.hops)] = read
+		resp.Body = &countingBody{ReadCloser: resp.Body, rec: rec, hop: read, limit: t.MaxTotalBytes}
+
+		hop := newHop(req, resp)
+		hop.Duration = time.Since(start)
+		hop.RequestID = requestID
+		hop.Cached = cached
+		hop.Replayed = replayed
+		hop.Protocol = protocolOf(resp)
+		hop.TLSVersion = tlsVersionOf(resp)
+		hop.RegisteredDomain = registeredDomain(req.URL.Hostname())
+		if t.Certificates {
+			hop.Certificate = newCertificate(resp.TLS, req.URL.Host)
+		}
+		if t.DNSRecords && !cached && !replayed {
+			hop.DNSRecords = t.lookupAll(ctx, req.URL.Hostname())
+			hop.RemoteAddr = info.remoteAddr
+		}
+		if t.CNAMEs && !cached && !replayed {
+			hop.CNAMEs = lookupCNAMEs(ctx, t.Resolver, t.DNSServer, req.URL.Hostname())
+		}
+		if isWebSocketProbe(req) {
+			hop.URL = websocketURL(hop.URL)
+			hop.WebSocket = probeResult(req, resp)
+		}
+		if !t.Punycode {
+			hop.URL = unicodeURL(hop.URL)
+			hop.Host = unicodeHost(hop.Host)
+			hop.RegisteredDomain = unicodeHost(hop.RegisteredDomain)
+			hop.RedirectURL = unicodeURL(hop.RedirectURL)
+		}
+		hop.ResolvedAddrs = info.resolvedAddrs
+		hop.Informational = info.informational
+		hop.Tunnel = info.tunnel
+		if timer != nil {
+			hop.Timing = timer.result()
+		}
+		if t.CaptureHeaders {
+			hop.Header = resp.Header.Clone()
+		}
+		if t.CaptureRequests {
+			hop.Request = newSentRequest(req, t.RevealSecrets)
+		}
+		rec.hops = append(rec.hops, hop)
+
+		if t.OnHop != nil {
+			t.OnHop(hop)
+		}
+	}
+
+	return resp, err
+}
+
+// newTransport returns a clone of opts.Transport which dials through dialer
+// and, when proxyURL is set, sends requests through that proxy.
+func newTransport(opts Options, dialer *net.Dialer, proxyURL *url.URL) (*http.Transport, error) {
+	base := opts.Transport
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+
+	if opts.DNSServer != "" {
+		transport.DialContext = dialer.DialContext
+	}
+
+	if opts.MaxHeaderBytes > 0 {
+		transport.MaxResponseHeaderBytes = opts.MaxHeaderBytes
+	}
+
+	if opts.Insecure {
+		tlsConfig := &tls.Config{}
+		if transport.TLSClientConfig != nil {
+			tlsConfig = transport.TLSClientConfig.Clone()
+		}
+		tlsConfig.InsecureSkipVerify = true
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if opts.MinTLSVersion != 0 {
+		tlsConfig := &tls.Config{}
+		if transport.TLSClientConfig != nil {
+			tlsConfig = transport.TLSClientConfig.Clone()
+		}
+		tlsConfig.MinVersion = opts.MinTLSVersion
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if opts.ConnectVia != "" {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialTunnel(ctx, dialer, opts.ConnectVia, addr)
+		}
+	}
+
+	if opts.UnixSocket != "" {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", opts.UnixSocket)
+		}
+	}
+
+	if proxyURL != nil {
+		if err := configureProxy(transport, proxyURL, dialer); err != nil {
+			return nil, err
+		}
+	}
+
+	return transport, nil
+}
+
+// lookupAll returns every address host resolves to, or nil when host is an IP
+// address or cannot be resolved.
+func (t *TransportWrapper) lookupAll(ctx context.Context, host string) []string {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	resolver := t.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	records := make([]string, len(addrs))
+	for i, addr := range addrs {
+		records[i] = addr.String()
+	}
+	return records
+}
+
+// hopTrace collects connection level details of a single round trip.
+type hopTrace struct {
+	resolvedAddrs []string
+	remoteAddr    string
+	informational []Informational
+
+	// tunnel is the proxy through which a CONNECT tunnel was opened for the
+	// round trip's new connection.
+	tunnel string
+}
+
+func (h *hopTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				h.remoteAddr = host
+			}
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			for _, addr := range info.Addrs {
+				h.resolvedAddrs = append(h.resolvedAddrs, addr.String())
+			}
+		},
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			h.informational = append(h.informational, Informational{StatusCode: code, Header: http.Header(header).Clone()})
+			return nil
+		},
+	}
+}
+
+// recorder collects the hops of a single trace. It travels with the request
+// context so that one transport may serve many traces.
+type recorder struct {
+	hops       []Hop
+	stopReason string
+
+	// initial is the input URL, whose domain alone receives the credentials
+	// in TransportWrapper.Header.
+	initial *url.URL
+
+	// jar holds the trace's cookies when Options.EnableCookies is set.
+	jar http.CookieJar
+
+	// bytes is the number of response body bytes read so far, updated
+	// atomically as bodies may be read while another hop is requested.
+	bytes int64
+
+	// hopBytes counts the body bytes read for each hop, by hop index.
+	hopBytes map[int]*int64
+}
+
+// countingBody adds the bytes read from a response body to its recorder,
+// failing once the trace has read more than limit bytes when limit is
+// positive.
+type countingBody struct {
+	io.ReadCloser
+	rec   *recorder
+	hop   *int64
+	limit int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(b.hop, int64(n))
+	total := atomic.AddInt64(&b.rec.bytes, int64(n))
+	if b.limit > 0 && total > b.limit {
+		return n, budgetError(b.limit)
+	}
+	return n, err
+}
+
+func budgetError(limit int64) error {
+	return &PolicyError{Reason: fmt.Sprintf("chain exceeded the total transfer limit of %d bytes", limit)}
+}
+
+type recorderKey struct{}
+
+func withRecorder(ctx context.Context, rec *recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, rec)
+}
+
+func recorderFrom(ctx context.Context) *recorder {
+	rec, _ := ctx.Value(recorderKey{}).(*recorder)
+	return rec
+}
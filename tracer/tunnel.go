@@ -0,0 +1,83 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// hopTraceKey is the context key of the hopTrace of a round trip, through
+// which a dialer can describe the connection it made.
+type hopTraceKey struct{}
+
+// dialTunnel connects to addr through an HTTP CONNECT tunnel opened on the
+// proxy at via, whatever the scheme of the request, and notes the tunnel on
+// the round trip's hopTrace.
+func dialTunnel(ctx context.Context, dialer *net.Dialer, via, addr string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", via)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to CONNECT tunnel %s: %v", via, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error requesting CONNECT tunnel %s to %s: %v", via, addr, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error reading CONNECT tunnel %s response for %s: %v", via, addr, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("CONNECT tunnel %s refused %s: %s", via, addr, resp.Status)
+	}
+
+	if info, ok := ctx.Value(hopTraceKey{}).(*hopTrace); ok {
+		info.tunnel = via
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn whose first bytes were read ahead into r.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
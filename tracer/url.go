@@ -0,0 +1,139 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// InvalidURL is the error recorded when an input URL, or a redirect target,
+// cannot be traced.
+type InvalidURL struct {
+	// URL is the offending URL as it was given.
+	URL string
+
+	// Reason describes why the URL cannot be traced.
+	Reason string
+}
+
+func (e *InvalidURL) Error() string {
+	return fmt.Sprintf("invalid URL %q: %s", e.URL, e.Reason)
+}
+
+// PolicyError is the error recorded when a redirect policy aborts the chain
+// rather than simply ending it at the last response.
+type PolicyError struct {
+	// Reason describes which policy was violated and by which hop.
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "redirect policy violated: " + e.Reason
+}
+
+// FailFastError is returned by Run when Options.FailFast stopped the batch.
+type FailFastError struct {
+	// URL is the input URL whose result triggered the stop.
+	URL string
+
+	// Skipped is the number of URLs which were not traced.
+	Skipped int
+}
+
+func (e *FailFastError) Error() string {
+	return fmt.Sprintf("stopped after %s failed, skipping %d remaining URL(s)", e.URL, e.Skipped)
+}
+
+// DefaultMaxURLLength is a generous limit for Options.MaxURLLength. Browsers
+// and servers commonly reject URLs far shorter than this.
+const DefaultMaxURLLength = 32 << 10
+
+// shownURLLength is how much of an overlong URL an error includes.
+const shownURLLength = 200
+
+// checkURLLength returns a *PolicyError when raw, the redirect target of hop,
+// is longer than limit bytes. A limit of zero or less disables the check.
+func checkURLLength(raw string, hop, limit int) error {
+	if limit <= 0 || len(raw) <= limit {
+		return nil
+	}
+
+	shown := raw
+	if len(shown) > shownURLLength {
+		shown = shown[:shownURLLength] + "..."
+	}
+	return &PolicyError{Reason: fmt.Sprintf("redirect from hop %d is to a URL of %d bytes, exceeding the %d byte limit: %s", hop, len(raw), limit, shown)}
+}
+
+// canonicalURL returns raw with its scheme and host lower cased, any default
+// port removed and an empty path written as "/", keeping the fragment. When
+// sortQuery is set the query parameters are put in order by name. raw itself
+// is returned if it cannot be parsed.
+func canonicalURL(raw string, sortQuery bool) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	c := *u
+	c.Scheme = strings.ToLower(u.Scheme)
+	c.Host = normalHost(u)
+	if c.Path == "" && c.Opaque == "" {
+		c.Path = "/"
+		c.RawPath = ""
+	}
+	if sortQuery && u.RawQuery != "" {
+		// Encode sorts by name, keeping the order of repeated parameters.
+		c.RawQuery = u.Query().Encode()
+	}
+	return c.String()
+}
+
+// parseInput parses a URL given to the Tracer. Inputs without a scheme, such
+// as "example.com:8080/path", are treated as http URLs.
+func parseInput(rawURL string) (*url.URL, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, &InvalidURL{URL: rawURL, Reason: err.Error()}
+	}
+
+	if err := validateURL(u); err != nil {
+		return nil, err
+	}
+	if err := toASCIIHost(u); err != nil {
+		return nil, &InvalidURL{URL: rawURL, Reason: "invalid internationalized host: " + err.Error()}
+	}
+	return u, nil
+}
+
+// validateURL ensures u uses a scheme the Tracer can follow and names a host.
+func validateURL(u *url.URL) error {
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	default:
+		return &InvalidURL{URL: u.String(), Reason: fmt.Sprintf("unsupported scheme %q, only http and https are supported", u.Scheme)}
+	}
+
+	if u.Host == "" {
+		return &InvalidURL{URL: u.String(), Reason: "missing host"}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// websocketGUID is appended to the client's key to form the accept value of
+// the handshake, as RFC 6455 section 1.3 specifies.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket describes a hop whose URL is a ws: or wss: WebSocket endpoint.
+type WebSocket struct {
+	// Probed is set when the upgrade handshake was attempted, as
+	// Options.ProbeWebSocket asks. An endpoint which was not probed has no
+	// status.
+	Probed bool `json:"probed"`
+
+	// Upgraded is set when a probed endpoint answered with 101 Switching
+	// Protocols and a valid Sec-WebSocket-Accept header.
+	Upgraded bool `json:"upgraded,omitempty"`
+}
+
+// isWebSocketScheme reports whether scheme is ws or wss.
+func isWebSocketScheme(scheme string) bool {
+	return strings.EqualFold(scheme, "ws") || strings.EqualFold(scheme, "wss")
+}
+
+// prepareWebSocketProbe turns req, a redirect to a ws: or wss: URL, into the
+// upgrade handshake over the matching http or https URL.
+func prepareWebSocketProbe(req *http.Request) error {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("error generating WebSocket key: %v", err)
+	}
+
+	if strings.EqualFold(req.URL.Scheme, "wss") {
+		req.URL.Scheme = "https"
+	} else {
+		req.URL.Scheme = "http"
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+// isWebSocketProbe reports whether req is an upgrade handshake made by
+// prepareWebSocketProbe.
+func isWebSocketProbe(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") && req.Header.Get("Sec-WebSocket-Key") != ""
+}
+
+// probeResult describes the response to the handshake req.
+func probeResult(req *http.Request, resp *http.Response) *WebSocket {
+	sum := sha1.Sum([]byte(req.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	accept := base64.StdEncoding.EncodeToString(sum[:])
+
+	return &WebSocket{
+		Probed:   true,
+		Upgraded: resp.StatusCode == http.StatusSwitchingProtocols && resp.Header.Get("Sec-WebSocket-Accept") == accept,
+	}
+}
+
+// websocketURL returns rawURL, an http or https URL, with the matching ws or
+// wss scheme.
+func websocketURL(rawURL string) string {
+	if rest, ok := strings.CutPrefix(rawURL, "https:"); ok {
+		return "wss:" + rest
+	}
+	if rest, ok := strings.CutPrefix(rawURL, "http:"); ok {
+		return "ws:" + rest
+	}
+	return rawURL
+}
@@ -0,0 +1,50 @@
+// Copyright © 2016 Kevin Kirsche <kevin.kirsche@verizon.com> <kev.kirsche@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// AbandonedError is the error recorded on a trace which Options.WorkerTimeout
+// gave up on.
+type AbandonedError struct {
+	// Timeout is the worker timeout which was exceeded.
+	Timeout string
+}
+
+func (e *AbandonedError) Error() string {
+	return fmt.Sprintf("abandoned (worker timeout of %s exceeded)", e.Timeout)
+}
+
+// traceAbandoning runs trace, giving up on it once Options.WorkerTimeout has
+// passed. The trace's context is then cancelled, and the batch waits only for
+// the cancelled trace to unwind, so that OnHop, FollowFunc and the other
+// callbacks are never running for two traces at once.
+func (t *Tracer) traceAbandoning(ctx context.Context, r Request, trace func(context.Context, Request) *TraceResult) *TraceResult {
+	workerCtx, cancel := context.WithTimeout(ctx, t.opts.WorkerTimeout)
+	defer cancel()
+
+	result := trace(workerCtx, r)
+	// A trace still running when the deadline passed was cut off, even when
+	// it returns without an error, such as from a retry backoff with the
+	// last response. It keeps its partial chain but is abandoned.
+	if errors.Is(workerCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		result.Err = &AbandonedError{Timeout: t.opts.WorkerTimeout.String()}
+	}
+	return result
+}